@@ -0,0 +1,46 @@
+package main
+
+import (
+	"coredemo/framework"
+	"net/http"
+)
+
+func UserLoginController(c *framework.Context) error {
+	return c.Text("ok, UserLoginController")
+}
+
+func SubjectListController(c *framework.Context) error {
+	page, _ := c.QueryInt("page", 1)
+	size, _ := c.QueryInt("size", 10)
+	return c.JSON(map[string]int{
+		"page": page,
+		"size": size,
+	})
+}
+
+func SubjectGetController(c *framework.Context) error {
+	return c.Text("ok, SubjectGetController: %s", c.Param("id"))
+}
+
+func SubjectUpdateController(c *framework.Context) error {
+	name, _ := c.FormString("name", "")
+	return c.Text("ok, SubjectUpdateController: %s %s", c.Param("id"), name)
+}
+
+func SubjectDelController(c *framework.Context) error {
+	return c.Text("ok, SubjectDelController: %s", c.Param("id"))
+}
+
+func SubjectAddController(c *framework.Context) error {
+	var subject struct {
+		Name string `json:"name"`
+	}
+	if err := c.BindJSON(&subject); err != nil {
+		return c.SetStatus(http.StatusBadRequest).Text("%s", err)
+	}
+	return c.SetStatus(http.StatusCreated).JSON(subject)
+}
+
+func StaticController(c *framework.Context) error {
+	return c.Text("ok, StaticController: %s", c.Param("filepath"))
+}
@@ -0,0 +1,101 @@
+package framework
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Context 请求上下文，封装 http.ResponseWriter 和 *http.Request
+// 实现 context.Context，超时、取消及值都委托给请求的 Context
+type Context struct {
+	request        *http.Request
+	responseWriter *responseWriter
+	// 路由中 :param 和 *wildcard 段匹配到的值
+	params map[string]string
+	// 中间件及处理函数组成的调用链
+	handlers []ControllerHandler
+	// 当前调用到调用链的位置
+	index int
+	// 响应状态码，写入响应体时发送
+	status int
+
+	// 保护超时后处理函数与超时中间件的并发写入
+	writerMux sync.Mutex
+	// 是否已经超时，超时后通过 Context 的写入都会被丢弃
+	hasTimeout bool
+}
+
+// NewContext 初始化请求上下文
+func NewContext(request *http.Request, response http.ResponseWriter) *Context {
+	return &Context{
+		request:        request,
+		responseWriter: &responseWriter{ResponseWriter: response},
+		status:         http.StatusOK,
+		index:          -1,
+	}
+}
+
+// GetRequest 返回原始请求
+func (ctx *Context) GetRequest() *http.Request {
+	return ctx.request
+}
+
+// SetRequest 替换请求，如超时中间件替换请求的 Context
+func (ctx *Context) SetRequest(request *http.Request) {
+	ctx.request = request
+}
+
+// GetResponse 返回记录了状态码的 http.ResponseWriter
+// 直接通过它写入的响应不受超时保护
+func (ctx *Context) GetResponse() http.ResponseWriter {
+	return ctx.responseWriter
+}
+
+// SetParams 设置路由参数
+func (ctx *Context) SetParams(params map[string]string) {
+	ctx.params = params
+}
+
+// SetHandlers 设置调用链
+func (ctx *Context) SetHandlers(handlers []ControllerHandler) {
+	ctx.handlers = handlers
+}
+
+// Next 调用调用链中的下一个处理函数，中间件在其中调用 Next 形成洋葱模型
+// 中间件不调用 Next 时，后续的处理函数不会被执行
+func (ctx *Context) Next() error {
+	ctx.index++
+	if ctx.index < len(ctx.handlers) {
+		if err := ctx.handlers[ctx.index](ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BaseContext 返回请求的 Context
+func (ctx *Context) BaseContext() context.Context {
+	return ctx.request.Context()
+}
+
+// Deadline 实现 context.Context
+func (ctx *Context) Deadline() (deadline time.Time, ok bool) {
+	return ctx.BaseContext().Deadline()
+}
+
+// Done 实现 context.Context
+func (ctx *Context) Done() <-chan struct{} {
+	return ctx.BaseContext().Done()
+}
+
+// Err 实现 context.Context
+func (ctx *Context) Err() error {
+	return ctx.BaseContext().Err()
+}
+
+// Value 实现 context.Context
+func (ctx *Context) Value(key interface{}) interface{} {
+	return ctx.BaseContext().Value(key)
+}
@@ -1,17 +1,118 @@
 package framework
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ControllerHandler 路由处理函数，中间件也是 ControllerHandler，通过 c.Next() 调用后续的处理函数
+type ControllerHandler func(c *Context) error
 
 // Core 框架核心结构
 type Core struct {
+	// 按 HTTP 方法区分的路由前缀树
+	router map[string]*Tree
+	// 全局中间件
+	middlewares []ControllerHandler
+
+	// 服务启动前及关闭后执行的钩子，按注册顺序
+	hooks []lifecycle
+	// 优雅关闭的等待时间
+	shutdownTimeout time.Duration
 }
 
 // NewCore 初始化框架核心结构
 func NewCore() *Core {
-	return &Core{}
+	router := map[string]*Tree{}
+	router[http.MethodGet] = NewTree()
+	router[http.MethodPost] = NewTree()
+	router[http.MethodPut] = NewTree()
+	router[http.MethodDelete] = NewTree()
+	return &Core{
+		router:          router,
+		shutdownTimeout: defaultShutdownTimeout,
+	}
+}
+
+// Get 注册 GET 方法的路由，handlers 为路由级的中间件及处理函数
+func (c *Core) Get(url string, handlers ...ControllerHandler) {
+	c.addRouter(http.MethodGet, url, handlers)
+}
+
+// Post 注册 POST 方法的路由，handlers 为路由级的中间件及处理函数
+func (c *Core) Post(url string, handlers ...ControllerHandler) {
+	c.addRouter(http.MethodPost, url, handlers)
+}
+
+// Put 注册 PUT 方法的路由，handlers 为路由级的中间件及处理函数
+func (c *Core) Put(url string, handlers ...ControllerHandler) {
+	c.addRouter(http.MethodPut, url, handlers)
+}
+
+// Delete 注册 DELETE 方法的路由，handlers 为路由级的中间件及处理函数
+func (c *Core) Delete(url string, handlers ...ControllerHandler) {
+	c.addRouter(http.MethodDelete, url, handlers)
+}
+
+// Use 注册全局中间件，只对之后注册的路由生效
+func (c *Core) Use(middlewares ...ControllerHandler) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// Group 创建路由前缀为 prefix 的路由分组
+func (c *Core) Group(prefix string) IGroup {
+	return NewGroup(c, prefix)
+}
+
+// addRouter 注册路由，调用链为全局中间件加上 handlers，路由冲突属于编码错误，直接 panic
+func (c *Core) addRouter(method, url string, handlers []ControllerHandler) {
+	if len(handlers) == 0 {
+		panic(fmt.Sprintf("add %s router error: route %s has no handler", method, url))
+	}
+
+	allHandlers := make([]ControllerHandler, 0, len(c.middlewares)+len(handlers))
+	allHandlers = append(allHandlers, c.middlewares...)
+	allHandlers = append(allHandlers, handlers...)
+	if err := c.router[method].AddRouter(url, allHandlers); err != nil {
+		panic(fmt.Sprintf("add %s router error: %v", method, err))
+	}
 }
 
 // ServeHTTP 框架核心结构实现 Handler 接口
 func (c *Core) ServeHTTP(response http.ResponseWriter, request *http.Request) {
-	// TODO
+	if tree, ok := c.router[request.Method]; ok {
+		if handlers, params := tree.FindHandlers(request.URL.Path); handlers != nil {
+			ctx := NewContext(request, response)
+			ctx.SetParams(params)
+			ctx.SetHandlers(handlers)
+			// 调用链返回错误且还没有写入响应时返回 500
+			if err := ctx.Next(); err != nil && !ctx.Written() {
+				http.Error(ctx.GetResponse(), err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+	}
+
+	// 其他方法能匹配时返回 405，否则返回 404
+	if allowed := c.allowedMethods(request.URL.Path); len(allowed) > 0 {
+		response.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(response, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(response, request)
+}
+
+// allowedMethods 返回能匹配 path 的方法
+func (c *Core) allowedMethods(path string) []string {
+	var allowed []string
+	for method, tree := range c.router {
+		if handlers, _ := tree.FindHandlers(path); handlers != nil {
+			allowed = append(allowed, method)
+		}
+	}
+	sort.Strings(allowed)
+	return allowed
 }
@@ -0,0 +1,90 @@
+package framework
+
+// IGroup 路由分组
+type IGroup interface {
+	// Get 注册 GET 方法的路由
+	Get(string, ...ControllerHandler)
+	// Post 注册 POST 方法的路由
+	Post(string, ...ControllerHandler)
+	// Put 注册 PUT 方法的路由
+	Put(string, ...ControllerHandler)
+	// Delete 注册 DELETE 方法的路由
+	Delete(string, ...ControllerHandler)
+
+	// Group 创建嵌套的路由分组
+	Group(string) IGroup
+	// Use 注册分组的中间件
+	Use(middlewares ...ControllerHandler)
+}
+
+// Group 路由分组，路由前缀及中间件继承自上级分组
+type Group struct {
+	core   *Core
+	parent *Group
+	// 相对上级分组的路由前缀
+	prefix string
+	// 分组自身的中间件
+	middlewares []ControllerHandler
+}
+
+// NewGroup 初始化路由分组
+func NewGroup(core *Core, prefix string) *Group {
+	return &Group{
+		core:   core,
+		prefix: prefix,
+	}
+}
+
+// Get 注册 GET 方法的路由
+func (g *Group) Get(uri string, handlers ...ControllerHandler) {
+	g.core.Get(g.getAbsolutePrefix()+uri, g.withMiddlewares(handlers)...)
+}
+
+// Post 注册 POST 方法的路由
+func (g *Group) Post(uri string, handlers ...ControllerHandler) {
+	g.core.Post(g.getAbsolutePrefix()+uri, g.withMiddlewares(handlers)...)
+}
+
+// Put 注册 PUT 方法的路由
+func (g *Group) Put(uri string, handlers ...ControllerHandler) {
+	g.core.Put(g.getAbsolutePrefix()+uri, g.withMiddlewares(handlers)...)
+}
+
+// Delete 注册 DELETE 方法的路由
+func (g *Group) Delete(uri string, handlers ...ControllerHandler) {
+	g.core.Delete(g.getAbsolutePrefix()+uri, g.withMiddlewares(handlers)...)
+}
+
+// Group 创建嵌套的路由分组
+func (g *Group) Group(uri string) IGroup {
+	cgroup := NewGroup(g.core, uri)
+	cgroup.parent = g
+	return cgroup
+}
+
+// Use 注册分组的中间件，只对之后注册的路由生效
+func (g *Group) Use(middlewares ...ControllerHandler) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// getAbsolutePrefix 返回包含所有上级分组前缀的完整前缀
+func (g *Group) getAbsolutePrefix() string {
+	if g.parent == nil {
+		return g.prefix
+	}
+	return g.parent.getAbsolutePrefix() + g.prefix
+}
+
+// getMiddlewares 返回由外到内的所有上级分组及自身的中间件，每次返回新的切片
+func (g *Group) getMiddlewares() []ControllerHandler {
+	var middlewares []ControllerHandler
+	if g.parent != nil {
+		middlewares = g.parent.getMiddlewares()
+	}
+	return append(middlewares, g.middlewares...)
+}
+
+// withMiddlewares 返回分组的中间件加上 handlers
+func (g *Group) withMiddlewares(handlers []ControllerHandler) []ControllerHandler {
+	return append(g.getMiddlewares(), handlers...)
+}
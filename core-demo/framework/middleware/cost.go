@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"coredemo/framework"
+	"log"
+	"time"
+)
+
+// Cost 记录请求的方法、路径、状态码及耗时
+func Cost() framework.ControllerHandler {
+	return func(c *framework.Context) error {
+		start := time.Now()
+
+		err := c.Next()
+
+		request := c.GetRequest()
+		if err != nil {
+			log.Printf("api uri: %s, method: %s, status: %d, cost: %v, error: %v",
+				request.URL.Path, request.Method, c.Status(), time.Since(start), err)
+		} else {
+			log.Printf("api uri: %s, method: %s, status: %d, cost: %v",
+				request.URL.Path, request.Method, c.Status(), time.Since(start))
+		}
+		return err
+	}
+}
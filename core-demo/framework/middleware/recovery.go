@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"coredemo/framework"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime/debug"
+)
+
+// panicWithStack 在其他 goroutine 中捕获的 panic 及其堆栈，由超时中间件在请求 goroutine 中重新抛出
+type panicWithStack struct {
+	value interface{}
+	stack []byte
+}
+
+func (p *panicWithStack) String() string {
+	return fmt.Sprint(p.value)
+}
+
+// Recovery 捕获调用链中的 panic，记录堆栈并返回 500
+func Recovery() framework.ControllerHandler {
+	return func(c *framework.Context) error {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			// http.ErrAbortHandler 用于中止请求，交给 net/http 处理
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+
+			stack := debug.Stack()
+			if ps, ok := p.(*panicWithStack); ok {
+				p, stack = ps.value, ps.stack
+			}
+			log.Printf("panic recovered: %s %s: %v\n%s", c.GetRequest().Method, c.GetRequest().URL.Path, p, stack)
+
+			if !c.Written() {
+				c.SetStatus(http.StatusInternalServerError).Text(http.StatusText(http.StatusInternalServerError))
+			}
+		}()
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"context"
+	"coredemo/framework"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Timeout 限制后续调用链的执行时间，超时后返回 504
+// 后续调用链在单独的 goroutine 中执行，超时后通过 Context 的写入都会被丢弃，
+// 处理函数应当监听 c.Done() 尽快退出
+func Timeout(d time.Duration) framework.ControllerHandler {
+	return func(c *framework.Context) error {
+		finish := make(chan error, 1)
+		panicChan := make(chan *panicWithStack)
+		// Timeout 返回后关闭，之后的 panic 没有人接收，在 goroutine 中记录
+		done := make(chan struct{})
+		defer close(done)
+
+		durationCtx, cancel := context.WithTimeout(c.BaseContext(), d)
+		defer cancel()
+		request := c.GetRequest().WithContext(durationCtx)
+		c.SetRequest(request)
+
+		go func() {
+			defer func() {
+				if p := recover(); p != nil {
+					ps, ok := p.(*panicWithStack)
+					if !ok {
+						ps = &panicWithStack{value: p, stack: debug.Stack()}
+					}
+
+					select {
+					case panicChan <- ps:
+					case <-done:
+						log.Printf("panic after timeout: %s %s: %v\n%s", request.Method, request.URL.Path,
+							ps.value, ps.stack)
+					}
+				}
+			}()
+
+			finish <- c.Next()
+		}()
+
+		select {
+		case p := <-panicChan:
+			// 在请求 goroutine 中重新抛出，交给 Recovery 处理
+			panic(p)
+		case err := <-finish:
+			return err
+		case <-durationCtx.Done():
+			c.WriteTimeout(http.StatusGatewayTimeout)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"bytes"
+	"coredemo/framework"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimeoutConcurrentStatus(t *testing.T) {
+	handlerDone := make(chan error, 1)
+	core := framework.NewCore()
+	core.Get("/slow", Timeout(20*time.Millisecond), func(c *framework.Context) error {
+		// 超时前后持续读取响应状态，与超时响应的写入并发
+		deadline := time.Now().Add(100 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			c.Status()
+			c.Written()
+		}
+		handlerDone <- c.SetStatus(http.StatusOK).Text("too late")
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	core.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if err := <-handlerDone; err != framework.ErrHasTimeout {
+		t.Fatalf("Text() after timeout = %v, want %v", err, framework.ErrHasTimeout)
+	}
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+	if body := w.Body.String(); strings.Contains(body, "too late") {
+		t.Fatalf("body = %q, want no writes after timeout", body)
+	}
+}
+
+// logWriter 收集日志，写入时通知等待方
+type logWriter struct {
+	lock    sync.Mutex
+	buf     bytes.Buffer
+	written chan struct{}
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	n, err := w.buf.Write(p)
+	select {
+	case w.written <- struct{}{}:
+	default:
+	}
+	return n, err
+}
+
+func (w *logWriter) String() string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.buf.String()
+}
+
+func TestTimeoutPanicAfterTimeout(t *testing.T) {
+	logs := &logWriter{written: make(chan struct{}, 1)}
+	prev := log.Writer()
+	log.SetOutput(logs)
+	defer log.SetOutput(prev)
+
+	core := framework.NewCore()
+	core.Get("/panic", Recovery(), Timeout(10*time.Millisecond), func(c *framework.Context) error {
+		// 等超时响应写入后再 panic，此时 Timeout 已经返回
+		for !c.HasTimeout() {
+			time.Sleep(time.Millisecond)
+		}
+		panic("late boom")
+	})
+
+	w := httptest.NewRecorder()
+	core.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusGatewayTimeout)
+	}
+
+	select {
+	case <-logs.written:
+	case <-time.After(time.Second):
+		t.Fatal("panic after timeout is not logged")
+	}
+	out := logs.String()
+	if !strings.Contains(out, "panic after timeout: GET /panic: late boom") || !strings.Contains(out, "goroutine") {
+		t.Fatalf("log = %q, want the panic and its stack", out)
+	}
+}
@@ -0,0 +1,113 @@
+package framework
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// 解析 multipart 表单时使用的最大内存
+const defaultMultipartMemory = 32 << 20
+
+// Param 返回路由参数，不存在时返回空字符串
+func (ctx *Context) Param(key string) string {
+	return ctx.params[key]
+}
+
+// QueryAll 返回所有 query 参数
+func (ctx *Context) QueryAll() map[string][]string {
+	if ctx.request != nil {
+		return ctx.request.URL.Query()
+	}
+	return map[string][]string{}
+}
+
+// QueryInt 返回 int 类型的 query 参数，不存在或无法解析时返回 def 及 false
+func (ctx *Context) QueryInt(key string, def int) (int, bool) {
+	return intValue(ctx.QueryAll(), key, def)
+}
+
+// QueryString 返回 string 类型的 query 参数，不存在时返回 def 及 false
+func (ctx *Context) QueryString(key string, def string) (string, bool) {
+	return stringValue(ctx.QueryAll(), key, def)
+}
+
+// QueryArray 返回 query 参数的所有值，不存在时返回 def 及 false
+func (ctx *Context) QueryArray(key string, def []string) ([]string, bool) {
+	return arrayValue(ctx.QueryAll(), key, def)
+}
+
+// FormAll 返回请求体中所有的表单参数，支持 urlencoded 及 multipart 表单
+func (ctx *Context) FormAll() map[string][]string {
+	if ctx.request == nil {
+		return map[string][]string{}
+	}
+
+	// multipart 表单的值也会解析到 PostForm，非 multipart 表单时返回 ErrNotMultipart
+	if err := ctx.request.ParseMultipartForm(defaultMultipartMemory); err != nil &&
+		!errors.Is(err, http.ErrNotMultipart) {
+		return map[string][]string{}
+	}
+	return ctx.request.PostForm
+}
+
+// FormInt 返回 int 类型的表单参数，不存在或无法解析时返回 def 及 false
+func (ctx *Context) FormInt(key string, def int) (int, bool) {
+	return intValue(ctx.FormAll(), key, def)
+}
+
+// FormString 返回 string 类型的表单参数，不存在时返回 def 及 false
+func (ctx *Context) FormString(key string, def string) (string, bool) {
+	return stringValue(ctx.FormAll(), key, def)
+}
+
+// FormArray 返回表单参数的所有值，不存在时返回 def 及 false
+func (ctx *Context) FormArray(key string, def []string) ([]string, bool) {
+	return arrayValue(ctx.FormAll(), key, def)
+}
+
+// BindJSON 将 JSON 请求体解析到 obj
+func (ctx *Context) BindJSON(obj interface{}) error {
+	if ctx.request == nil || ctx.request.Body == nil {
+		return errors.New("request body is empty")
+	}
+	return json.NewDecoder(ctx.request.Body).Decode(obj)
+}
+
+// BindXML 将 XML 请求体解析到 obj
+func (ctx *Context) BindXML(obj interface{}) error {
+	if ctx.request == nil || ctx.request.Body == nil {
+		return errors.New("request body is empty")
+	}
+	return xml.NewDecoder(ctx.request.Body).Decode(obj)
+}
+
+// 多个值时取最后一个
+func stringValue(values map[string][]string, key string, def string) (string, bool) {
+	if vals, ok := values[key]; ok && len(vals) > 0 {
+		return vals[len(vals)-1], true
+	}
+	return def, false
+}
+
+func intValue(values map[string][]string, key string, def int) (int, bool) {
+	val, ok := stringValue(values, key, "")
+	if !ok {
+		return def, false
+	}
+
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		return def, false
+	}
+	return i, true
+}
+
+func arrayValue(values map[string][]string, key string, def []string) ([]string, bool) {
+	if vals, ok := values[key]; ok {
+		return vals, true
+	}
+	return def, false
+}
@@ -0,0 +1,171 @@
+package framework
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+// ErrHasTimeout is returned by the response helpers of Context after the request times out.
+var ErrHasTimeout = errors.New("request has timed out")
+
+// SetStatus 设置响应状态码，在写入响应体时发送
+func (ctx *Context) SetStatus(code int) *Context {
+	ctx.writerMux.Lock()
+	defer ctx.writerMux.Unlock()
+
+	ctx.status = code
+	return ctx
+}
+
+// SetHeader 设置响应头
+func (ctx *Context) SetHeader(key string, val string) *Context {
+	ctx.guard(func() error {
+		ctx.responseWriter.Header().Set(key, val)
+		return nil
+	})
+	return ctx
+}
+
+// SetCookie 设置 cookie
+func (ctx *Context) SetCookie(key string, val string, maxAge int, path string, domain string,
+	secure bool, httpOnly bool) *Context {
+	if path == "" {
+		path = "/"
+	}
+	ctx.guard(func() error {
+		http.SetCookie(ctx.responseWriter, &http.Cookie{
+			Name:     key,
+			Value:    val,
+			MaxAge:   maxAge,
+			Path:     path,
+			Domain:   domain,
+			Secure:   secure,
+			HttpOnly: httpOnly,
+		})
+		return nil
+	})
+	return ctx
+}
+
+// JSON 输出 JSON 响应
+func (ctx *Context) JSON(obj interface{}) error {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+
+	return ctx.write("application/json; charset=utf-8", body)
+}
+
+// HTML 使用模板文件 file 渲染 obj 并输出 HTML 响应
+func (ctx *Context) HTML(file string, obj interface{}) error {
+	t, err := template.ParseFiles(file)
+	if err != nil {
+		return err
+	}
+
+	return ctx.guard(func() error {
+		ctx.responseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
+		ctx.responseWriter.WriteHeader(ctx.status)
+		return t.Execute(ctx.responseWriter, obj)
+	})
+}
+
+// Text 输出格式化的文本响应
+func (ctx *Context) Text(format string, values ...interface{}) error {
+	return ctx.write("text/plain; charset=utf-8", []byte(fmt.Sprintf(format, values...)))
+}
+
+// Redirect 重定向到 path，状态码为 302
+func (ctx *Context) Redirect(path string) error {
+	return ctx.guard(func() error {
+		http.Redirect(ctx.responseWriter, ctx.request, path, http.StatusFound)
+		return nil
+	})
+}
+
+// Written 是否已经写入响应
+func (ctx *Context) Written() bool {
+	ctx.writerMux.Lock()
+	defer ctx.writerMux.Unlock()
+
+	return ctx.responseWriter.written
+}
+
+// Status 返回已经写入的状态码，还没有写入时返回 SetStatus 设置的状态码
+func (ctx *Context) Status() int {
+	ctx.writerMux.Lock()
+	defer ctx.writerMux.Unlock()
+
+	if ctx.responseWriter.written {
+		return ctx.responseWriter.status
+	}
+	return ctx.status
+}
+
+// WriteTimeout 标记请求已超时，之后通过 Context 写入的响应都会被丢弃
+// 还没有写入响应时在同一个临界区内写入状态码为 code 的超时响应，避免与处理函数并发写入
+func (ctx *Context) WriteTimeout(code int) {
+	ctx.writerMux.Lock()
+	defer ctx.writerMux.Unlock()
+
+	ctx.hasTimeout = true
+	if !ctx.responseWriter.written {
+		http.Error(ctx.responseWriter, http.StatusText(code), code)
+	}
+}
+
+// HasTimeout 是否已经超时
+func (ctx *Context) HasTimeout() bool {
+	ctx.writerMux.Lock()
+	defer ctx.writerMux.Unlock()
+
+	return ctx.hasTimeout
+}
+
+// guard 持有写锁执行 fn，超时后不再执行
+func (ctx *Context) guard(fn func() error) error {
+	ctx.writerMux.Lock()
+	defer ctx.writerMux.Unlock()
+
+	if ctx.hasTimeout {
+		return ErrHasTimeout
+	}
+	return fn()
+}
+
+func (ctx *Context) write(contentType string, body []byte) error {
+	return ctx.guard(func() error {
+		ctx.responseWriter.Header().Set("Content-Type", contentType)
+		ctx.responseWriter.WriteHeader(ctx.status)
+		_, err := ctx.responseWriter.Write(body)
+		return err
+	})
+}
+
+// responseWriter 记录状态码及是否已经写入响应
+type responseWriter struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.written {
+		return
+	}
+
+	w.status = code
+	w.written = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	if !w.written {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,140 @@
+package framework
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// 默认的优雅关闭等待时间
+const defaultShutdownTimeout = 10 * time.Second
+
+// Hook 服务启动或关闭时执行的钩子函数
+type Hook func(ctx context.Context) error
+
+// lifecycle 成对注册的启动及关闭钩子，任一个可以为 nil
+type lifecycle struct {
+	start Hook
+	stop  Hook
+}
+
+// OnLifecycle 成对注册服务启动前及关闭后执行的钩子，start 或 stop 可以为 nil
+// start 按注册顺序执行，任一 start 返回错误时服务不再启动；
+// stop 按注册的逆序执行，只执行 start 成功或没有 start 的钩子，ctx 的超时时间为优雅关闭的剩余时间
+func (c *Core) OnLifecycle(start, stop Hook) {
+	c.hooks = append(c.hooks, lifecycle{
+		start: start,
+		stop:  stop,
+	})
+}
+
+// OnStart 注册服务启动前执行的钩子，没有对应的关闭钩子，需要关闭的资源使用 OnLifecycle 注册
+func (c *Core) OnStart(hook Hook) {
+	c.OnLifecycle(hook, nil)
+}
+
+// OnStop 注册服务关闭后执行的钩子，无论服务是否启动成功都会执行，如刷新日志
+func (c *Core) OnStop(hook Hook) {
+	c.OnLifecycle(nil, hook)
+}
+
+// SetShutdownTimeout 设置优雅关闭的等待时间，默认 10s
+func (c *Core) SetShutdownTimeout(timeout time.Duration) {
+	c.shutdownTimeout = timeout
+}
+
+// Run 在 addr 上启动服务，收到 SIGINT/SIGTERM/SIGQUIT 时优雅关闭：
+// 不再接受新请求，等待处理中的请求结束，最多等待 shutdownTimeout，然后执行关闭钩子
+func (c *Core) Run(addr string) error {
+	// setup context and signal handling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer signal.Stop(quit)
+
+	// 没有 start 的钩子视为已经启动
+	started := make([]bool, len(c.hooks))
+	for i, hook := range c.hooks {
+		started[i] = hook.start == nil
+	}
+	for i, hook := range c.hooks {
+		if hook.start == nil {
+			continue
+		}
+		if err := hook.start(ctx); err != nil {
+			timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+			defer timeoutCancel()
+
+			c.stop(timeoutCtx, started)
+			return err
+		}
+		started[i] = true
+	}
+
+	g, ctx := errgroup.WithContext(ctx)
+
+	// start server
+	server := &http.Server{
+		Addr:    addr,
+		Handler: c,
+	}
+	g.Go(func() error {
+		log.Printf("server listening on %s", addr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+
+		return nil
+	})
+
+	// handle termination
+	select {
+	case sig := <-quit:
+		log.Printf("received signal %v", sig)
+	case <-ctx.Done():
+	}
+
+	// gracefully shutdown http server
+	timeoutCtx, timeoutCancel := context.WithTimeout(context.Background(), c.shutdownTimeout)
+	defer timeoutCancel()
+
+	log.Println("shutting down server, please wait...")
+	shutdownErr := server.Shutdown(timeoutCtx)
+
+	// wait for shutdown
+	err := g.Wait()
+	if stopErr := c.stop(timeoutCtx, started); err == nil {
+		if err = shutdownErr; err == nil {
+			err = stopErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Println("a graceful bye")
+	return nil
+}
+
+// stop 按逆序执行已经启动的钩子的 stop，返回第一个错误
+func (c *Core) stop(ctx context.Context, started []bool) error {
+	var stopErr error
+	for i := len(c.hooks) - 1; i >= 0; i-- {
+		hook := c.hooks[i]
+		if !started[i] || hook.stop == nil {
+			continue
+		}
+		if err := hook.stop(ctx); err != nil && stopErr == nil {
+			stopErr = err
+		}
+	}
+	return stopErr
+}
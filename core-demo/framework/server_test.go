@@ -0,0 +1,58 @@
+package framework
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// recordHook 返回把 name 记录到 calls 的钩子
+func recordHook(calls *[]string, name string, err error) Hook {
+	return func(ctx context.Context) error {
+		*calls = append(*calls, name)
+		return err
+	}
+}
+
+func TestRunStopsStartedHooksWhenStartFails(t *testing.T) {
+	core := NewCore()
+	var calls []string
+	errStart := errors.New("start failed")
+	core.OnStop(recordHook(&calls, "flush", nil))
+	core.OnLifecycle(recordHook(&calls, "start a", nil), recordHook(&calls, "stop a", nil))
+	core.OnStart(recordHook(&calls, "start b", errStart))
+	core.OnLifecycle(recordHook(&calls, "start c", nil), recordHook(&calls, "stop c", nil))
+	core.OnStop(recordHook(&calls, "close", nil))
+
+	if err := core.Run("127.0.0.1:0"); err != errStart {
+		t.Fatalf("Run() = %v, want %v", err, errStart)
+	}
+	// c 没有启动，不执行 stop c；只有 stop 的钩子总是执行
+	if want := []string{"start a", "start b", "close", "stop a", "flush"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("hooks called %v, want %v", calls, want)
+	}
+}
+
+func TestRunStopsHooksWhenListenFails(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	core := NewCore()
+	var calls []string
+	core.OnLifecycle(recordHook(&calls, "start a", nil), recordHook(&calls, "stop a", nil))
+	core.OnStart(recordHook(&calls, "start b", nil))
+	core.OnStop(recordHook(&calls, "flush", nil))
+
+	// 端口已被占用，ListenAndServe 失败
+	if err := core.Run(listener.Addr().String()); err == nil {
+		t.Fatal("Run() = nil, want listen error")
+	}
+	if want := []string{"start a", "start b", "flush", "stop a"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("hooks called %v, want %v", calls, want)
+	}
+}
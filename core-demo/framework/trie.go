@@ -0,0 +1,161 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Tree 路由前缀树，每个 HTTP 方法一棵
+type Tree struct {
+	root *node
+}
+
+// node 前缀树节点，对应路由中的一段
+type node struct {
+	// 是否是一个完整路由的终点
+	isLast bool
+	// 路由中的一段，如 "user"、":id"、"*filepath"
+	segment string
+	// 注册的完整路由，用于冲突提示
+	pattern string
+	// 中间件及处理函数组成的调用链
+	handlers []ControllerHandler
+	// 子节点
+	children []*node
+}
+
+// NewTree 初始化路由前缀树
+func NewTree() *Tree {
+	return &Tree{
+		root: &node{},
+	}
+}
+
+// isParamSegment 是否是 :param 段
+func isParamSegment(segment string) bool {
+	return strings.HasPrefix(segment, ":")
+}
+
+// isWildSegment 是否是 *wildcard 段
+func isWildSegment(segment string) bool {
+	return strings.HasPrefix(segment, "*")
+}
+
+// splitPath 按 / 切分路由，忽略首尾的 /
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// AddRouter 注册路由，与已注册的路由冲突时返回错误
+// 同一位置只能有一种参数名的 :param 段和一个 *wildcard 段，*wildcard 段只能是最后一段
+func (tree *Tree) AddRouter(pattern string, handlers []ControllerHandler) error {
+	segments := splitPath(pattern)
+	for i, segment := range segments {
+		if err := checkSegment(segment, i == len(segments)-1); err != nil {
+			return fmt.Errorf("route %s: %w", pattern, err)
+		}
+	}
+
+	n := tree.root
+	for _, segment := range segments {
+		child, err := n.childFor(segment)
+		if err != nil {
+			return fmt.Errorf("route %s: %w", pattern, err)
+		}
+		if child == nil {
+			child = &node{segment: segment}
+			n.children = append(n.children, child)
+		}
+		n = child
+	}
+
+	if n.isLast {
+		return fmt.Errorf("route %s conflicts with %s", pattern, n.pattern)
+	}
+	n.isLast = true
+	n.pattern = pattern
+	n.handlers = handlers
+	return nil
+}
+
+// checkSegment 检查路由中的一段是否合法
+func checkSegment(segment string, last bool) error {
+	switch {
+	case segment == "":
+		return errors.New("empty segment")
+	case (isParamSegment(segment) || isWildSegment(segment)) && len(segment) == 1:
+		return errors.New("parameter name must not be empty")
+	case isWildSegment(segment) && !last:
+		return errors.New("wildcard segment must be the last one")
+	}
+	return nil
+}
+
+// childFor 返回注册 segment 时复用的子节点，不存在时返回 nil
+// 同一位置已有不同名称的 :param 或 *wildcard 段时返回错误
+func (n *node) childFor(segment string) (*node, error) {
+	for _, child := range n.children {
+		if child.segment == segment {
+			return child, nil
+		}
+		if isParamSegment(segment) && isParamSegment(child.segment) ||
+			isWildSegment(segment) && isWildSegment(child.segment) {
+			return nil, fmt.Errorf("segment %s conflicts with %s", segment, child.segment)
+		}
+	}
+	return nil, nil
+}
+
+// FindHandlers 匹配 uri，返回调用链及路由参数，没有匹配的路由时返回 nil
+func (tree *Tree) FindHandlers(uri string) ([]ControllerHandler, map[string]string) {
+	params := make(map[string]string)
+	n := tree.root.match(splitPath(uri), params)
+	if n == nil {
+		return nil, nil
+	}
+	return n.handlers, params
+}
+
+// match 匹配剩余的 segments，优先级：静态段 > :param 段 > *wildcard 段，匹配失败时回溯
+func (n *node) match(segments []string, params map[string]string) *node {
+	if len(segments) == 0 {
+		if n.isLast {
+			return n
+		}
+		return nil
+	}
+
+	segment, rest := segments[0], segments[1:]
+	for _, child := range n.children {
+		if child.segment == segment && !isParamSegment(segment) && !isWildSegment(segment) {
+			if result := child.match(rest, params); result != nil {
+				return result
+			}
+		}
+	}
+
+	for _, child := range n.children {
+		if isParamSegment(child.segment) {
+			name := child.segment[1:]
+			params[name] = segment
+			if result := child.match(rest, params); result != nil {
+				return result
+			}
+			delete(params, name)
+		}
+	}
+
+	for _, child := range n.children {
+		if isWildSegment(child.segment) && child.isLast {
+			params[child.segment[1:]] = strings.Join(segments, "/")
+			return child
+		}
+	}
+
+	return nil
+}
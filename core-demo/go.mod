@@ -1,3 +1,5 @@
 module coredemo
 
 go 1.17
+
+require golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
@@ -2,16 +2,17 @@ package main
 
 import (
 	"coredemo/framework"
-	"net/http"
+	"log"
+	"time"
 )
 
 func main() {
-	// 创建 Server
-	server := &http.Server{
-		// 自定义的请求核心处理函数
-		Handler: framework.NewCore(),
-		// 请求监听端口
-		Addr:    ":8080",
+	core := framework.NewCore()
+	registerRouter(core)
+
+	// 收到退出信号后最多等待 5s 处理中的请求
+	core.SetShutdownTimeout(5 * time.Second)
+	if err := core.Run(":8080"); err != nil {
+		log.Fatal(err)
 	}
-	server.ListenAndServe()
 }
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"coredemo/framework"
+	"coredemo/framework/middleware"
+	"time"
+)
+
+// registerRouter 注册路由
+func registerRouter(core *framework.Core) {
+	core.Use(middleware.Cost(), middleware.Recovery())
+
+	core.Get("/user/login", UserLoginController)
+
+	subjectApi := core.Group("/subject")
+	subjectApi.Use(middleware.Timeout(time.Second))
+	{
+		subjectApi.Get("/list/all", SubjectListController)
+		subjectApi.Get("/:id", SubjectGetController)
+		subjectApi.Put("/:id", SubjectUpdateController)
+		subjectApi.Delete("/:id", SubjectDelController)
+		subjectApi.Post("", SubjectAddController)
+	}
+
+	core.Get("/static/*filepath", StaticController)
+}
@@ -1,19 +1,16 @@
 package breaker
 
 import (
+	"context"
 	"errors"
+	"examples/go-hystrix/collection"
 	"examples/go-hystrix/timex"
 	"fmt"
-	"github.com/tal-tech/go-zero/core/mathx"
 	"github.com/tal-tech/go-zero/core/proc"
 	"github.com/tal-tech/go-zero/core/stat"
-	"strings"
 	"sync"
-)
-
-const (
-	numHistoryReasons = 5
-	timeFormat        = "15:04:05"
+	"sync/atomic"
+	"time"
 )
 
 // ErrServiceUnavailable is returned when the Breaker state is open.
@@ -70,17 +67,72 @@ type Breaker interface {
 	// and causes the same panic again.
 	// acceptable checks if it's a successful call, even if the err is not nil.
 	DoWithFallbackAcceptable(req func() error, fallback func(err error) error, acceptable Acceptable) error
+
+	// DoCtx runs the given request with ctx if the Breaker accepts it.
+	// DoCtx returns ctx.Err() instantly without running the request if ctx is already done,
+	// and returns ctx.Err() as soon as ctx is done while the request is running.
+	// If the Breaker has a timeout, the request is cancelled after the timeout.
+	// A request cancelled by the caller (context.Canceled) is neither counted as a success nor a failure.
+	DoCtx(ctx context.Context, req func(ctx context.Context) error) error
+
+	// DoCtxWithAcceptable is the same as DoCtx, acceptable checks if it's a successful call.
+	DoCtxWithAcceptable(ctx context.Context, req func(ctx context.Context) error, acceptable Acceptable) error
+
+	// DoCtxWithFallback is the same as DoCtx, and runs the fallback if the Breaker rejects the request.
+	DoCtxWithFallback(ctx context.Context, req func(ctx context.Context) error,
+		fallback func(err error) error) error
+
+	// DoCtxWithFallbackAcceptable is the same as DoCtxWithAcceptable,
+	// and runs the fallback if the Breaker rejects the request.
+	DoCtxWithFallbackAcceptable(ctx context.Context, req func(ctx context.Context) error,
+		fallback func(err error) error, acceptable Acceptable) error
+
+	// RecentErrors returns the recent errors that failed the requests, the latest first.
+	// The same errors are merged into one record with the repeat count.
+	RecentErrors() []ErrorRecord
+
+	// Snapshot serializes the statistics of the Breaker with their timestamps,
+	// so that they can be restored by Restore after the process restarts.
+	Snapshot() ([]byte, error)
+
+	// Restore merges the statistics in the snapshot into the Breaker,
+	// the statistics already out of the window are discarded.
+	Restore(data []byte) error
 }
 
+// NewBreaker returns a Breaker customized by opts.
+// NewBreaker panics if the given options are invalid.
 func NewBreaker(opts ...Option) Breaker {
-	var b circuitBreaker
+	b := circuitBreaker{
+		clock:        timex.RealClock(),
+		window:       defaultWindow,
+		buckets:      defaultBuckets,
+		k:            defaultK,
+		protection:   defaultProtection,
+		errorHistory: defaultErrorHistory,
+	}
 	for _, opt := range opts {
 		opt(&b)
 	}
 	if len(b.name) == 0 {
 		b.name = "random name"
 	}
-	b.throttle = newLoggedThrottle(b.name, newGoogleBreaker())
+	b.validate()
+
+	// lt 在 throttle 创建完成后才赋值，状态变化回调中通过闭包引用
+	var lt loggedThrottle
+	onStateChange := func(from, to State) {
+		lt.stateChanged(from, to)
+	}
+	var t internalThrottle
+	if b.hystrixConf != nil {
+		t = newHystrixBreaker(*b.hystrixConf, b.newRollingWindow, b.clock, onStateChange)
+	} else {
+		t = newGoogleBreaker(b.k, b.protection, b.newRollingWindow(), onStateChange)
+	}
+	lt = newLoggedThrottle(b.name, t, newErrorWindow(b.errorHistory, b.clock), b.listeners,
+		newBulkhead(b.maxConcurrency))
+	b.throttle = lt
 
 	return &b
 }
@@ -92,6 +144,24 @@ func NewBreaker(opts ...Option) Breaker {
 // -> internalThrottle      熔断器内部核心实现 (代理 loggedThrottle)
 type circuitBreaker struct {
 	name string
+	// 时钟，滑动窗口及错误日志均以此计时
+	clock timex.Clock
+	// 滑动窗口时长及桶数
+	window  time.Duration
+	buckets int
+	// googleBreaker 敏感度及保护请求数
+	k          float64
+	protection int64
+	// 非空时使用三态熔断器代替 googleBreaker
+	hystrixConf *HystrixConfig
+	// 事件监听器
+	listeners []Listener
+	// DoCtx 系列方法的执行超时时间，0 表示不限制
+	timeout time.Duration
+	// 最大并发请求数，0 表示不限制
+	maxConcurrency int
+	// 保留的最近错误条数
+	errorHistory int
 	// throttle circuitBreaker 的静态代理, 熔断功能代理代理给 throttle 实现
 	throttle
 }
@@ -100,6 +170,39 @@ type circuitBreaker struct {
 type throttle interface {
 	allow() (Promise, error)
 	doReq(req func() error, fallback func(err error) error, acceptable Acceptable) error
+	doCtxReq(req func(done func()) error, fallback func(err error) error, acceptable Acceptable) error
+}
+
+func (cb *circuitBreaker) validate() {
+	if cb.window <= 0 {
+		panic("window must be greater than 0")
+	}
+	if cb.buckets < 1 {
+		panic("buckets must be greater than 0")
+	}
+	if cb.window/time.Duration(cb.buckets) <= 0 {
+		panic("window must be longer than buckets nanoseconds")
+	}
+	if cb.k < 1 {
+		panic("k must not be less than 1")
+	}
+	if cb.protection < 0 {
+		panic("protection must not be negative")
+	}
+	if cb.timeout < 0 {
+		panic("timeout must not be negative")
+	}
+	if cb.maxConcurrency < 0 {
+		panic("max concurrency must not be negative")
+	}
+	if cb.errorHistory < 1 {
+		panic("error history must be greater than 0")
+	}
+}
+
+func (cb *circuitBreaker) newRollingWindow() *collection.RollingWindow {
+	bucketDuration := time.Duration(int64(cb.window) / int64(cb.buckets))
+	return collection.NewRollingWindow(cb.buckets, bucketDuration, collection.WithClock(cb.clock))
 }
 
 func (cb *circuitBreaker) Allow() (Promise, error) {
@@ -123,10 +226,86 @@ func (cb *circuitBreaker) DoWithFallbackAcceptable(req func() error, fallback fu
 	return cb.throttle.doReq(req, fallback, acceptable)
 }
 
+func (cb *circuitBreaker) DoCtx(ctx context.Context, req func(ctx context.Context) error) error {
+	return cb.doCtx(ctx, req, nil, defaultAcceptable)
+}
+
+func (cb *circuitBreaker) DoCtxWithAcceptable(ctx context.Context, req func(ctx context.Context) error,
+	acceptable Acceptable) error {
+	return cb.doCtx(ctx, req, nil, acceptable)
+}
+
+func (cb *circuitBreaker) DoCtxWithFallback(ctx context.Context, req func(ctx context.Context) error,
+	fallback func(err error) error) error {
+	return cb.doCtx(ctx, req, fallback, defaultAcceptable)
+}
+
+func (cb *circuitBreaker) DoCtxWithFallbackAcceptable(ctx context.Context, req func(ctx context.Context) error,
+	fallback func(err error) error, acceptable Acceptable) error {
+	return cb.doCtx(ctx, req, fallback, acceptable)
+}
+
 func (cb *circuitBreaker) Name() string {
 	return cb.name
 }
 
+func (cb *circuitBreaker) RecentErrors() []ErrorRecord {
+	return cb.throttle.(loggedThrottle).errWin.recent()
+}
+
+func (cb *circuitBreaker) Snapshot() ([]byte, error) {
+	return cb.throttle.(loggedThrottle).snapshot()
+}
+
+func (cb *circuitBreaker) Restore(data []byte) error {
+	return cb.throttle.(loggedThrottle).restore(data)
+}
+
+func (cb *circuitBreaker) doCtx(ctx context.Context, req func(ctx context.Context) error,
+	fallback func(err error) error, acceptable Acceptable) error {
+	// 调用方已取消或超时，不占用熔断器统计
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return cb.throttle.doCtxReq(func(done func()) error {
+		return cb.runCtx(ctx, req, done)
+	}, fallback, acceptable)
+}
+
+// runCtx 在新的 goroutine 中执行请求，ctx 结束时立即返回 ctx.Err()
+// 请求真正返回时在请求 goroutine 中调用 done，ctx 结束后仍在执行的请求继续占用并发名额
+// 请求中的 panic 会在调用方 goroutine 中重新抛出，由熔断器记为失败
+func (cb *circuitBreaker) runCtx(ctx context.Context, req func(ctx context.Context) error, done func()) error {
+	if cb.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cb.timeout)
+		defer cancel()
+	}
+
+	// 带缓冲，ctx 结束后请求 goroutine 仍可写入并退出
+	finish := make(chan error, 1)
+	panicChan := make(chan interface{}, 1)
+	go func() {
+		defer done()
+		defer func() {
+			if p := recover(); p != nil {
+				panicChan <- p
+			}
+		}()
+		finish <- req(ctx)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case err := <-finish:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func defaultAcceptable(err error) bool {
 	return err == nil
 }
@@ -136,96 +315,216 @@ type loggedThrottle struct {
 	name string
 	// 代理对象
 	internalThrottle
-	// 滑动窗口，滚动收集请求失败时的错误日志
+	// 环形缓冲区，滚动收集请求失败时的错误
 	errWin *errorWindow
+	// 事件监听器
+	listeners []Listener
+	// 累计计数，用于导出监控指标
+	counters *counters
+	// 并发限制，nil 表示不限制
+	bulkhead *bulkhead
 }
 
-func newLoggedThrottle(name string, t internalThrottle) loggedThrottle {
+func newLoggedThrottle(name string, t internalThrottle, errWin *errorWindow, listeners []Listener,
+	bh *bulkhead) loggedThrottle {
 	return loggedThrottle{
 		name:             name,
 		internalThrottle: t,
-		errWin:           new(errorWindow),
+		errWin:           errWin,
+		listeners:        listeners,
+		counters:         new(counters),
+		bulkhead:         bh,
 	}
 }
 
+// allow 获取并发名额后再交给熔断器判断，返回的 promise 在 Accept 或 Reject 时释放名额
 func (lt loggedThrottle) allow() (Promise, error) {
+	if !lt.bulkhead.acquire() {
+		return nil, lt.logError(ErrMaxConcurrency)
+	}
+
 	promise, err := lt.internalThrottle.allow()
+	if err != nil {
+		lt.bulkhead.release()
+		return nil, lt.logError(err)
+	}
+
+	var once sync.Once
 	return promiseWithReason{
 		promise: promise,
-		errWin:  lt.errWin,
-	}, lt.logError(err)
+		lt:      lt,
+		release: func() {
+			once.Do(lt.bulkhead.release)
+		},
+	}, nil
 }
 
 func (lt loggedThrottle) doReq(req func() error, fallback func(err error) error, acceptable Acceptable) error {
-	return lt.logError(lt.internalThrottle.doReq(req, fallback, func(err error) bool {
+	if !lt.bulkhead.acquire() {
+		err := lt.logError(ErrMaxConcurrency)
+		if fallback != nil {
+			return fallback(err)
+		}
+		return err
+	}
+	defer lt.bulkhead.release()
+
+	// 熔断时先记录日志再执行 fallback，避免 fallback 吞掉 ErrServiceUnavailable 后丢失 drop 事件
+	return lt.internalThrottle.doReq(req, func(err error) error {
+		lt.logError(err)
+		if fallback != nil {
+			return fallback(err)
+		}
+		return err
+	}, func(err error) bool {
 		accept := acceptable(err)
-		if !accept {
-			lt.errWin.add(err.Error())
+		if accept {
+			lt.success()
+		} else {
+			lt.failure(errorType(err), err.Error())
 		}
 		return accept
-	}))
+	})
+}
+
+// doCtxReq 与 doReq 相同，但调用方主动取消的请求(context.Canceled)既不计为成功也不计为失败，
+// 半开状态下占用的探测名额会被归还
+// req 可能在请求真正结束前返回，并发名额在请求结束时由 req 调用 done 释放
+func (lt loggedThrottle) doCtxReq(req func(done func()) error, fallback func(err error) error,
+	acceptable Acceptable) error {
+	if !lt.bulkhead.acquire() {
+		err := lt.logError(ErrMaxConcurrency)
+		if fallback != nil {
+			return fallback(err)
+		}
+		return err
+	}
+
+	promise, err := lt.internalThrottle.allow()
+	if err != nil {
+		lt.bulkhead.release()
+		lt.logError(err)
+		if fallback != nil {
+			return fallback(err)
+		}
+		return err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			promise.Reject()
+			panic(e)
+		}
+	}()
+
+	err = req(lt.bulkhead.release)
+	switch {
+	case errors.Is(err, context.Canceled):
+		promise.Ignore()
+	case acceptable(err):
+		lt.success()
+		promise.Accept()
+	default:
+		lt.failure(errorType(err), err.Error())
+		promise.Reject()
+	}
+	return err
 }
 
 func (lt loggedThrottle) logError(err error) error {
-	if err == ErrServiceUnavailable {
+	switch err {
+	case ErrServiceUnavailable:
 		// if circuit open, not possible to have empty error window
 		stat.Report(fmt.Sprintf(
 			"proc(%s/%d), callee: %s, breaker is open and requests dropped\nlast errors:\n%s",
 			proc.ProcessName(), proc.Pid(), lt.name, lt.errWin))
+		atomic.AddInt64(&lt.counters.drops, 1)
+	case ErrMaxConcurrency:
+		lt.errWin.add(errorType(err), err.Error())
+		atomic.AddInt64(&lt.counters.concurrencyDrops, 1)
+	default:
+		return err
 	}
 
+	lt.notify(func(l Listener, st Stat) {
+		l.OnReject(st, err)
+	})
 	return err
 }
 
-// 滑动窗口
-type errorWindow struct {
-	reasons [numHistoryReasons]string
-	index   int
-	count   int
-	lock    sync.Mutex
+func (lt loggedThrottle) success() {
+	atomic.AddInt64(&lt.counters.accepts, 1)
+	lt.notify(func(l Listener, st Stat) {
+		l.OnSuccess(st)
+	})
 }
 
-func (ew *errorWindow) add(reason string) {
-	ew.lock.Lock()
-	ew.reasons[ew.index] = fmt.Sprintf("%s %s", timex.Time().Format(timeFormat), reason)
-	ew.index = (ew.index + 1) % numHistoryReasons
-	ew.count = mathx.MinInt(ew.count+1, numHistoryReasons)
-	ew.lock.Unlock()
+func (lt loggedThrottle) failure(typ, reason string) {
+	lt.errWin.add(typ, reason)
+	atomic.AddInt64(&lt.counters.failures, 1)
+	lt.notify(func(l Listener, st Stat) {
+		l.OnFailure(st, reason)
+	})
 }
 
-// String 格式化错误日志
-func (ew *errorWindow) String() string {
-	var reasons []string
+func (lt loggedThrottle) stateChanged(from, to State) {
+	atomic.StoreInt32(&lt.counters.state, int32(to))
+	lt.notify(func(l Listener, st Stat) {
+		l.OnStateChange(st, from, to)
+	})
+}
 
-	ew.lock.Lock()
-	for i := ew.index - 1; i >= ew.index-ew.count; i-- {
-		reasons = append(reasons, ew.reasons[(i+numHistoryReasons)%numHistoryReasons])
+// notify 将当前统计数据分发给所有监听器，没有监听器时不做统计
+func (lt loggedThrottle) notify(fn func(l Listener, st Stat)) {
+	if len(lt.listeners) == 0 {
+		return
 	}
-	ew.lock.Unlock()
 
-	return strings.Join(reasons, "\n")
+	accepts, total, dropRatio := lt.internalThrottle.metrics()
+	st := Stat{
+		Name:      lt.name,
+		Accepts:   accepts,
+		Total:     total,
+		DropRatio: dropRatio,
+	}
+	for _, l := range lt.listeners {
+		fn(l, st)
+	}
 }
 
 type internalPromise interface {
 	Accept()
 	Reject()
+	// Ignore 放弃本次请求的结果，不计入统计
+	Ignore()
 }
 
 type promiseWithReason struct {
 	promise internalPromise
-	errWin  *errorWindow
+	lt      loggedThrottle
+	// 释放并发名额，多次调用只释放一次
+	release func()
 }
 
 func (p promiseWithReason) Accept() {
+	p.release()
 	p.promise.Accept()
+	p.lt.success()
 }
 
 func (p promiseWithReason) Reject(reason string) {
-	p.errWin.add(reason)
+	p.release()
+	p.lt.failure("", reason)
 	p.promise.Reject()
 }
 
 type internalThrottle interface {
 	allow() (internalPromise, error)
 	doReq(req func() error, fallback func(err error) error, acceptable Acceptable) error
+	// metrics 返回滑动窗口内的成功数、总数及当前的熔断概率
+	metrics() (accepts, total int64, dropRatio float64)
+	// snapshot 序列化滑动窗口
+	snapshot() ([]byte, error)
+	// restore 从快照恢复滑动窗口
+	restore(data []byte) error
 }
@@ -0,0 +1,112 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/timex"
+	"testing"
+	"time"
+)
+
+var errDownstream = errors.New("downstream error")
+
+func TestDoCtxCanceledHalfOpenProbe(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	b := NewBreaker(WithClock(clock), WithHystrix(HystrixConfig{
+		ConsecutiveFailures: 1,
+		SleepWindow:         time.Second,
+	}))
+
+	if err := b.Do(func() error {
+		return errDownstream
+	}); err != errDownstream {
+		t.Fatalf("Do() = %v, want %v", err, errDownstream)
+	}
+	if err := b.Do(func() error {
+		return nil
+	}); err != ErrServiceUnavailable {
+		t.Fatalf("Do() on open breaker = %v, want %v", err, ErrServiceUnavailable)
+	}
+
+	// 进入半开状态后，被调用方取消的探测请求不应关闭熔断器
+	clock.Advance(time.Second)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := b.DoCtx(context.Background(), func(context.Context) error {
+		return ctx.Err()
+	}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("DoCtx() = %v, want %v", err, context.Canceled)
+	}
+
+	// 探测名额已归还，下一个探测请求失败后熔断器重新打开
+	if err := b.Do(func() error {
+		return errDownstream
+	}); err != errDownstream {
+		t.Fatalf("probe Do() = %v, want %v", err, errDownstream)
+	}
+	if err := b.Do(func() error {
+		return nil
+	}); err != ErrServiceUnavailable {
+		t.Fatalf("Do() after failed probe = %v, want %v", err, ErrServiceUnavailable)
+	}
+}
+
+func TestDoCtxCanceledNotCounted(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	b := NewBreaker(WithClock(clock))
+	for i := 0; i < 100; i++ {
+		b.DoCtx(context.Background(), func(context.Context) error {
+			return context.Canceled
+		})
+	}
+
+	accepts, total, _ := b.(*circuitBreaker).throttle.(loggedThrottle).metrics()
+	if accepts != 0 || total != 0 {
+		t.Fatalf("metrics() = %d/%d, want 0/0", accepts, total)
+	}
+}
+
+func TestDoCtxBulkheadHeldUntilReturn(t *testing.T) {
+	b := NewBreaker(WithMaxConcurrency(1), WithTimeout(10*time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+
+	// 忽略 ctx 的请求超时返回后仍在执行，继续占用并发名额
+	if err := b.DoCtx(context.Background(), func(context.Context) error {
+		<-release
+		return nil
+	}); err != context.DeadlineExceeded {
+		t.Fatalf("DoCtx() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := b.DoCtx(context.Background(), func(context.Context) error {
+		return nil
+	}); err != ErrMaxConcurrency {
+		t.Fatalf("DoCtx() while the previous request is running = %v, want %v", err, ErrMaxConcurrency)
+	}
+}
+
+func TestDoCtxBulkheadReleasedAfterReturn(t *testing.T) {
+	b := NewBreaker(WithMaxConcurrency(1), WithTimeout(10*time.Millisecond))
+	returned := make(chan struct{})
+	b.DoCtx(context.Background(), func(ctx context.Context) error {
+		defer close(returned)
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	<-returned
+
+	// 请求 goroutine 在 done 之后才释放名额，等待其退出
+	deadline := time.Now().Add(time.Second)
+	for {
+		err := b.DoCtx(context.Background(), func(context.Context) error {
+			return nil
+		})
+		if err == nil {
+			return
+		}
+		if err != ErrMaxConcurrency || time.Now().After(deadline) {
+			t.Fatalf("DoCtx() after the previous request returned = %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,103 @@
+package breaker
+
+import (
+	"sort"
+	"sync"
+)
+
+// 全局熔断器注册表，按名称(通常是下游服务名)复用熔断器
+var (
+	lock     sync.RWMutex
+	breakers = make(map[string]Breaker)
+)
+
+// Do calls Breaker.Do on the Breaker with given name.
+func Do(name string, req func() error) error {
+	return do(name, func(b Breaker) error {
+		return b.Do(req)
+	})
+}
+
+// DoWithAcceptable calls Breaker.DoWithAcceptable on the Breaker with given name.
+func DoWithAcceptable(name string, req func() error, acceptable Acceptable) error {
+	return do(name, func(b Breaker) error {
+		return b.DoWithAcceptable(req, acceptable)
+	})
+}
+
+// DoWithFallback calls Breaker.DoWithFallback on the Breaker with given name.
+func DoWithFallback(name string, req func() error, fallback func(err error) error) error {
+	return do(name, func(b Breaker) error {
+		return b.DoWithFallback(req, fallback)
+	})
+}
+
+// DoWithFallbackAcceptable calls Breaker.DoWithFallbackAcceptable on the Breaker with given name.
+func DoWithFallbackAcceptable(name string, req func() error, fallback func(err error) error,
+	acceptable Acceptable) error {
+	return do(name, func(b Breaker) error {
+		return b.DoWithFallbackAcceptable(req, fallback, acceptable)
+	})
+}
+
+// GetBreaker returns the Breaker with given name, creates one with opts if not exists.
+// opts only take effect on creation, and the name is always the given name.
+func GetBreaker(name string, opts ...Option) Breaker {
+	b, ok := lookupBreaker(name)
+	if ok {
+		return b
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	// double check，避免并发创建同名熔断器
+	if b, ok = breakers[name]; ok {
+		return b
+	}
+
+	b = NewBreaker(append(opts[:len(opts):len(opts)], WithName(name))...)
+	breakers[name] = b
+	return b
+}
+
+// lookupBreaker 返回已注册的熔断器，不存在时不创建
+func lookupBreaker(name string) (Breaker, bool) {
+	lock.RLock()
+	b, ok := breakers[name]
+	lock.RUnlock()
+	return b, ok
+}
+
+// Names returns the names of all the registered breakers in order, mostly used for debugging.
+func Names() []string {
+	lock.RLock()
+	names := make([]string, 0, len(breakers))
+	for name := range breakers {
+		names = append(names, name)
+	}
+	lock.RUnlock()
+
+	sort.Strings(names)
+	return names
+}
+
+// Breakers returns all the registered breakers ordered by name, mostly used for debugging.
+func Breakers() []Breaker {
+	names := Names()
+	bs := make([]Breaker, 0, len(names))
+
+	lock.RLock()
+	for _, name := range names {
+		if b, ok := breakers[name]; ok {
+			bs = append(bs, b)
+		}
+	}
+	lock.RUnlock()
+
+	return bs
+}
+
+func do(name string, execute func(b Breaker) error) error {
+	return execute(GetBreaker(name))
+}
@@ -0,0 +1,53 @@
+package breaker
+
+import "errors"
+
+// ErrMaxConcurrency is returned when the in-flight requests of the Breaker reach the limit.
+var ErrMaxConcurrency = errors.New("circuit breaker max concurrency exceeded")
+
+// bulkhead 信号量隔离，限制同时执行的请求数
+// nil 表示不限制
+type bulkhead struct {
+	sem chan struct{}
+}
+
+func newBulkhead(maxConcurrency int) *bulkhead {
+	if maxConcurrency <= 0 {
+		return nil
+	}
+
+	return &bulkhead{
+		sem: make(chan struct{}, maxConcurrency),
+	}
+}
+
+// acquire 非阻塞地获取一个执行名额，名额用完时返回 false
+func (b *bulkhead) acquire() bool {
+	if b == nil {
+		return true
+	}
+
+	select {
+	case b.sem <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *bulkhead) release() {
+	if b == nil {
+		return
+	}
+
+	<-b.sem
+}
+
+// inflight 返回正在执行的请求数
+func (b *bulkhead) inflight() int {
+	if b == nil {
+		return 0
+	}
+
+	return len(b.sem)
+}
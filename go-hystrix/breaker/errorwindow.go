@@ -0,0 +1,116 @@
+package breaker
+
+import (
+	"examples/go-hystrix/timex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultErrorHistory = 5
+	timeFormat          = "15:04:05"
+)
+
+// ErrorRecord 熔断器记录的一条错误，相同类型及内容的错误合并为一条
+type ErrorRecord struct {
+	// 错误类型，如 *net.OpError，Promise.Reject 上报的原因为空
+	Type string
+	// 错误内容
+	Message string
+	// 出现的次数
+	Count int
+	// 第一次及最近一次出现的时间
+	FirstTime time.Time
+	LastTime  time.Time
+}
+
+func (r ErrorRecord) String() string {
+	var sb strings.Builder
+	sb.WriteString(r.LastTime.Format(timeFormat))
+	if len(r.Type) > 0 {
+		fmt.Fprintf(&sb, " [%s]", r.Type)
+	}
+	sb.WriteString(" ")
+	sb.WriteString(r.Message)
+	if r.Count > 1 {
+		fmt.Fprintf(&sb, " (x%d since %s)", r.Count, r.FirstTime.Format(timeFormat))
+	}
+	return sb.String()
+}
+
+// errorWindow 保存最近 size 条不同的错误，由旧到新排列
+// 已存在的错误再次出现时累加次数并移动到最新的位置
+type errorWindow struct {
+	records []ErrorRecord
+	size    int
+	lock    sync.Mutex
+	clock   timex.Clock
+}
+
+func newErrorWindow(size int, clock timex.Clock) *errorWindow {
+	return &errorWindow{
+		records: make([]ErrorRecord, 0, size),
+		size:    size,
+		clock:   clock,
+	}
+}
+
+func (ew *errorWindow) add(typ, message string) {
+	now := ew.clock.Time()
+
+	ew.lock.Lock()
+	defer ew.lock.Unlock()
+
+	for i, r := range ew.records {
+		if r.Type == typ && r.Message == message {
+			r.Count++
+			r.LastTime = now
+			// 移动到最新的位置
+			copy(ew.records[i:], ew.records[i+1:])
+			ew.records[len(ew.records)-1] = r
+			return
+		}
+	}
+
+	if len(ew.records) == ew.size {
+		// 丢弃最旧的错误
+		copy(ew.records, ew.records[1:])
+		ew.records = ew.records[:len(ew.records)-1]
+	}
+	ew.records = append(ew.records, ErrorRecord{
+		Type:      typ,
+		Message:   message,
+		Count:     1,
+		FirstTime: now,
+		LastTime:  now,
+	})
+}
+
+// recent 返回最近的错误，最新的在前
+func (ew *errorWindow) recent() []ErrorRecord {
+	ew.lock.Lock()
+	defer ew.lock.Unlock()
+
+	records := make([]ErrorRecord, len(ew.records))
+	for i, r := range ew.records {
+		records[len(records)-1-i] = r
+	}
+	return records
+}
+
+// String 格式化错误日志，最新的在前
+func (ew *errorWindow) String() string {
+	var reasons []string
+	for _, r := range ew.recent() {
+		reasons = append(reasons, r.String())
+	}
+
+	return strings.Join(reasons, "\n")
+}
+
+// errorType 返回错误的具体类型
+func errorType(err error) string {
+	return fmt.Sprintf("%T", err)
+}
@@ -0,0 +1,216 @@
+package breaker
+
+import (
+	"bufio"
+	"examples/go-hystrix/collection"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// counters 熔断器创建以来的累计计数
+type counters struct {
+	accepts          int64
+	failures         int64
+	drops            int64
+	concurrencyDrops int64
+	state            int32
+}
+
+// breakerMetrics 导出时熔断器的指标快照
+type breakerMetrics struct {
+	accepts          int64
+	failures         int64
+	drops            int64
+	concurrencyDrops int64
+	inflight         int
+	state            State
+	windowAccepts    int64
+	windowTotal      int64
+	dropRatio        float64
+}
+
+func (cb *circuitBreaker) metrics() breakerMetrics {
+	lt := cb.throttle.(loggedThrottle)
+	accepts, total, dropRatio := lt.internalThrottle.metrics()
+	return breakerMetrics{
+		accepts:          atomic.LoadInt64(&lt.counters.accepts),
+		failures:         atomic.LoadInt64(&lt.counters.failures),
+		drops:            atomic.LoadInt64(&lt.counters.drops),
+		concurrencyDrops: atomic.LoadInt64(&lt.counters.concurrencyDrops),
+		inflight:         lt.bulkhead.inflight(),
+		state:            State(atomic.LoadInt32(&lt.counters.state)),
+		windowAccepts:    accepts,
+		windowTotal:      total,
+		dropRatio:        dropRatio,
+	}
+}
+
+// An Exporter exposes the metrics of the registered breakers and rolling windows
+// in Prometheus text exposition format.
+type Exporter struct {
+	lock     sync.RWMutex
+	breakers map[string]*circuitBreaker
+	windows  map[string]*collection.RollingWindow
+}
+
+// NewExporter returns an Exporter.
+func NewExporter() *Exporter {
+	return &Exporter{
+		breakers: make(map[string]*circuitBreaker),
+		windows:  make(map[string]*collection.RollingWindow),
+	}
+}
+
+// Register registers b by its name, it returns an error if the name is already registered
+// or b is not created by NewBreaker.
+func (e *Exporter) Register(b Breaker) error {
+	cb, ok := b.(*circuitBreaker)
+	if !ok {
+		return fmt.Errorf("breaker %q is not created by NewBreaker", b.Name())
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if _, ok := e.breakers[cb.name]; ok {
+		return fmt.Errorf("breaker %q is already registered", cb.name)
+	}
+	e.breakers[cb.name] = cb
+	return nil
+}
+
+// Unregister removes the breaker with given name.
+func (e *Exporter) Unregister(name string) {
+	e.lock.Lock()
+	delete(e.breakers, name)
+	e.lock.Unlock()
+}
+
+// RegisterWindow registers rw by name, it returns an error if the name is already registered.
+func (e *Exporter) RegisterWindow(name string, rw *collection.RollingWindow) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if _, ok := e.windows[name]; ok {
+		return fmt.Errorf("rolling window %q is already registered", name)
+	}
+	e.windows[name] = rw
+	return nil
+}
+
+// UnregisterWindow removes the rolling window with given name.
+func (e *Exporter) UnregisterWindow(name string) {
+	e.lock.Lock()
+	delete(e.windows, name)
+	e.lock.Unlock()
+}
+
+// ServeHTTP writes the metrics of all registered breakers and rolling windows.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	bw := bufio.NewWriter(w)
+	e.write(bw)
+	bw.Flush()
+}
+
+// write writes the metrics in Prometheus text exposition format to w.
+func (e *Exporter) write(w *bufio.Writer) {
+	e.lock.RLock()
+	names := make([]string, 0, len(e.breakers))
+	breakers := make(map[string]breakerMetrics, len(e.breakers))
+	for name, cb := range e.breakers {
+		names = append(names, name)
+		breakers[name] = cb.metrics()
+	}
+	windowNames := make([]string, 0, len(e.windows))
+	windows := make(map[string]collection.Bucket, len(e.windows))
+	for name, rw := range e.windows {
+		var sum collection.Bucket
+		rw.Reduce(func(b *collection.Bucket) {
+			sum.Sum += b.Sum
+			sum.Count += b.Count
+		})
+		windowNames = append(windowNames, name)
+		windows[name] = sum
+	}
+	e.lock.RUnlock()
+
+	sort.Strings(names)
+	sort.Strings(windowNames)
+	writeFamily(w, "breaker_requests_total", "counter", "Total requests seen by the breaker.",
+		names, func(name string) float64 {
+			m := breakers[name]
+			return float64(m.accepts + m.failures + m.drops + m.concurrencyDrops)
+		})
+	writeFamily(w, "breaker_accepts_total", "counter", "Total requests accepted as successful calls.",
+		names, func(name string) float64 {
+			return float64(breakers[name].accepts)
+		})
+	writeFamily(w, "breaker_failures_total", "counter", "Total requests failed.",
+		names, func(name string) float64 {
+			return float64(breakers[name].failures)
+		})
+	writeFamily(w, "breaker_drops_total", "counter", "Total requests dropped by the breaker.",
+		names, func(name string) float64 {
+			return float64(breakers[name].drops)
+		})
+	writeFamily(w, "breaker_concurrency_drops_total", "counter",
+		"Total requests rejected for exceeding the max concurrency.",
+		names, func(name string) float64 {
+			return float64(breakers[name].concurrencyDrops)
+		})
+	writeFamily(w, "breaker_inflight_requests", "gauge", "Current in-flight requests limited by the max concurrency.",
+		names, func(name string) float64 {
+			return float64(breakers[name].inflight)
+		})
+	writeFamily(w, "breaker_drop_ratio", "gauge", "Current drop ratio of the breaker.",
+		names, func(name string) float64 {
+			return breakers[name].dropRatio
+		})
+	writeFamily(w, "breaker_state", "gauge", "Current state of the breaker, 0 closed, 1 open, 2 half-open.",
+		names, func(name string) float64 {
+			return float64(breakers[name].state)
+		})
+	writeFamily(w, "breaker_window_requests", "gauge", "Requests in the rolling window of the breaker.",
+		names, func(name string) float64 {
+			return float64(breakers[name].windowTotal)
+		})
+	writeFamily(w, "breaker_window_accepts", "gauge", "Accepted requests in the rolling window of the breaker.",
+		names, func(name string) float64 {
+			return float64(breakers[name].windowAccepts)
+		})
+
+	writeFamily(w, "rolling_window_sum", "gauge", "Sum of the values in the rolling window.",
+		windowNames, func(name string) float64 {
+			return windows[name].Sum
+		})
+	writeFamily(w, "rolling_window_count", "gauge", "Count of the values in the rolling window.",
+		windowNames, func(name string) float64 {
+			return float64(windows[name].Count)
+		})
+}
+
+func writeFamily(w *bufio.Writer, metric, typ, help string, names []string, value func(name string) float64) {
+	if len(names) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "# HELP %s %s\n", metric, help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", metric, typ)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s{name=\"%s\"} %v\n", metric, escapeLabel(name), value(name))
+	}
+}
+
+// labelReplacer 按 Prometheus 文本格式转义 label 值中的反斜杠、双引号及换行
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelReplacer.Replace(v)
+}
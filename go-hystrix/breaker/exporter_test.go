@@ -0,0 +1,119 @@
+package breaker
+
+import (
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, e *Exporter) string {
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := w.Header().Get("Content-Type"); ct != contentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, contentType)
+	}
+	body, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestExporterServeHTTP(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	b := NewBreaker(WithName("orders"), WithClock(clock), WithHystrix(HystrixConfig{
+		ConsecutiveFailures: 1,
+		SleepWindow:         time.Second,
+	}))
+	e := NewExporter()
+	if err := e.Register(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Register(b); err == nil {
+		t.Fatal("Register() twice = nil, want error")
+	}
+
+	// 2 次成功，1 次失败后熔断，再 1 次被丢弃
+	for i := 0; i < 2; i++ {
+		if err := b.Do(func() error {
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := b.Do(func() error {
+		return errDownstream
+	}); err != errDownstream {
+		t.Fatalf("Do() = %v, want %v", err, errDownstream)
+	}
+	if err := b.Do(func() error {
+		return nil
+	}); err != ErrServiceUnavailable {
+		t.Fatalf("Do() = %v, want %v", err, ErrServiceUnavailable)
+	}
+
+	rw := collection.NewRollingWindow(2, time.Second, collection.WithClock(clock))
+	rw.Add(1.5)
+	rw.Add(2)
+	if err := e.RegisterWindow("latency", rw); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrape(t, e)
+	for _, line := range []string{
+		"# TYPE breaker_requests_total counter",
+		"# TYPE breaker_accepts_total counter",
+		"# TYPE breaker_failures_total counter",
+		"# TYPE breaker_drops_total counter",
+		"# TYPE breaker_concurrency_drops_total counter",
+		"# TYPE breaker_inflight_requests gauge",
+		"# TYPE breaker_drop_ratio gauge",
+		"# TYPE breaker_state gauge",
+		"# TYPE breaker_window_requests gauge",
+		"# TYPE breaker_window_accepts gauge",
+		"# TYPE rolling_window_sum gauge",
+		"# TYPE rolling_window_count gauge",
+		`breaker_requests_total{name="orders"} 4`,
+		`breaker_accepts_total{name="orders"} 2`,
+		`breaker_failures_total{name="orders"} 1`,
+		`breaker_drops_total{name="orders"} 1`,
+		`breaker_concurrency_drops_total{name="orders"} 0`,
+		`breaker_inflight_requests{name="orders"} 0`,
+		`breaker_state{name="orders"} 1`,
+		`rolling_window_sum{name="latency"} 3.5`,
+		`rolling_window_count{name="latency"} 2`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing %q in:\n%s", line, body)
+		}
+	}
+
+	e.Unregister("orders")
+	e.UnregisterWindow("latency")
+	if body := scrape(t, e); body != "" {
+		t.Fatalf("body = %q, want empty after unregistering", body)
+	}
+}
+
+func TestExporterEscapeLabel(t *testing.T) {
+	e := NewExporter()
+	if err := e.Register(NewBreaker(WithName("a\"b\\c\nd"))); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrape(t, e)
+	want := `breaker_requests_total{name="a\"b\\c\nd"} 0` + "\n"
+	if !strings.Contains(body, want) {
+		t.Fatalf("missing %q in:\n%s", want, body)
+	}
+	// 每个样本占一行，换行已被转义
+	for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+		if !strings.HasPrefix(line, "# ") && !strings.HasPrefix(line, "breaker_") {
+			t.Fatalf("unexpected line %q", line)
+		}
+	}
+}
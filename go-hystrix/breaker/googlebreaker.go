@@ -4,33 +4,42 @@ import (
 	"examples/go-hystrix/collection"
 	"examples/go-hystrix/mathx"
 	"math"
+	"sync/atomic"
 	"time"
 )
 
 const (
 	// 1000 / 40 = 250ms for bucket duration
-	window     = time.Second * 10
-	buckets    = 40
-	k          = 1.5
-	protection = 5
+	defaultWindow     = time.Second * 10
+	defaultBuckets    = 40
+	defaultK          = 1.5
+	defaultProtection = 5
 )
 
 type googleBreaker struct {
 	// 敏感度
 	k float64
+	// 保护请求数，窗口内请求数不超过该值时不会熔断
+	protection int64
 	// 滑动窗口
 	stat *collection.RollingWindow
 	// 概率生成器：随机产生[0, 1] 之间的双精度浮点数
 	proba *mathx.Proba
+	// 熔断概率为 0 时视为 StateClosed，大于 0 时视为 StateOpen
+	state int32
+	// 状态变化回调
+	onStateChange func(from, to State)
 }
 
-func newGoogleBreaker() *googleBreaker {
-	bucketDuration := time.Duration(int64(window) / int64(buckets))
-	st := collection.NewRollingWindow(buckets, bucketDuration)
+func newGoogleBreaker(k float64, protection int64, st *collection.RollingWindow,
+	onStateChange func(from, to State)) *googleBreaker {
 	return &googleBreaker{
-		stat:  st,
-		k:     k,
-		proba: mathx.NewProba(),
+		stat:          st,
+		k:             k,
+		protection:    protection,
+		proba:         mathx.NewProba(),
+		state:         int32(StateClosed),
+		onStateChange: onStateChange,
 	}
 }
 
@@ -81,8 +90,8 @@ func (b *googleBreaker) accept() error {
 	// 获取最近一段时间内的统计数据
 	accepts, total := b.history()
 	// 计算动态熔断概率
-	weightedAccepts := b.k * float64(accepts)
-	dropRatio := math.Max(0, (float64(total-protection)-weightedAccepts)/float64(total+1))
+	dropRatio := b.dropRatio(accepts, total)
+	b.updateState(dropRatio)
 	// 概率为0，通过
 	if dropRatio <= 0 {
 		return nil
@@ -97,6 +106,36 @@ func (b *googleBreaker) accept() error {
 	return nil
 }
 
+func (b *googleBreaker) dropRatio(accepts, total int64) float64 {
+	weightedAccepts := b.k * float64(accepts)
+	return math.Max(0, (float64(total-b.protection)-weightedAccepts)/float64(total+1))
+}
+
+// updateState 熔断概率在 0 与正数之间变化时触发状态变化回调
+func (b *googleBreaker) updateState(dropRatio float64) {
+	to := StateClosed
+	if dropRatio > 0 {
+		to = StateOpen
+	}
+	from := State(atomic.SwapInt32(&b.state, int32(to)))
+	if from != to {
+		b.onStateChange(from, to)
+	}
+}
+
+func (b *googleBreaker) metrics() (accepts, total int64, dropRatio float64) {
+	accepts, total = b.history()
+	return accepts, total, b.dropRatio(accepts, total)
+}
+
+func (b *googleBreaker) snapshot() ([]byte, error) {
+	return b.stat.Snapshot()
+}
+
+func (b *googleBreaker) restore(data []byte) error {
+	return b.stat.Restore(data)
+}
+
 func (b *googleBreaker) history() (accepts, total int64) {
 	b.stat.Reduce(func(b *collection.Bucket) {
 		accepts += int64(b.Sum)
@@ -124,3 +163,7 @@ func (p googlePromise) Accept() {
 func (p googlePromise) Reject() {
 	p.b.markFailure()
 }
+
+// Ignore 放行时不做统计，忽略结果无需处理
+func (p googlePromise) Ignore() {
+}
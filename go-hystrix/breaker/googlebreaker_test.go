@@ -0,0 +1,87 @@
+package breaker
+
+import (
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"testing"
+	"time"
+)
+
+func newTestGoogleBreaker(clock timex.Clock, onStateChange func(from, to State)) *googleBreaker {
+	st := collection.NewRollingWindow(defaultBuckets, defaultWindow/defaultBuckets, collection.WithClock(clock))
+	return newGoogleBreaker(defaultK, defaultProtection, st, onStateChange)
+}
+
+func TestGoogleBreakerDropRatio(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	var changes []State
+	b := newTestGoogleBreaker(clock, func(from, to State) {
+		changes = append(changes, to)
+	})
+
+	// 保护请求数内的失败不熔断
+	for i := 0; i < defaultProtection; i++ {
+		b.markFailure()
+	}
+	if _, _, dropRatio := b.metrics(); dropRatio != 0 {
+		t.Fatalf("dropRatio = %v, want 0 within protection", dropRatio)
+	}
+
+	for i := 0; i < 95; i++ {
+		b.markFailure()
+	}
+	accepts, total, dropRatio := b.metrics()
+	if accepts != 0 || total != 100 {
+		t.Fatalf("metrics() = (%d, %d), want (0, 100)", accepts, total)
+	}
+	if want := float64(100-defaultProtection) / 101; dropRatio != want {
+		t.Fatalf("dropRatio = %v, want %v", dropRatio, want)
+	}
+	b.accept()
+	if len(changes) != 1 || changes[0] != StateOpen {
+		t.Fatalf("state changes = %v, want [%v]", changes, StateOpen)
+	}
+
+	// 窗口过去一半，失败仍在窗口内
+	clock.Advance(defaultWindow / 2)
+	if _, _, dropRatio := b.metrics(); dropRatio == 0 {
+		t.Fatal("dropRatio = 0, want failures still counted")
+	}
+
+	// 整个窗口过去后失败过期，不再熔断
+	clock.Advance(defaultWindow / 2)
+	if _, total, dropRatio := b.metrics(); total != 0 || dropRatio != 0 {
+		t.Fatalf("metrics() = (%d, %v), want (0, 0) after the window", total, dropRatio)
+	}
+	if err := b.accept(); err != nil {
+		t.Fatalf("accept() = %v, want nil", err)
+	}
+	if len(changes) != 2 || changes[1] != StateClosed {
+		t.Fatalf("state changes = %v, want [%v %v]", changes, StateOpen, StateClosed)
+	}
+}
+
+func TestGoogleBreakerSuccessesKeepClosed(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	b := newTestGoogleBreaker(clock, func(from, to State) {
+		t.Fatalf("state changed from %v to %v", from, to)
+	})
+
+	// k = 1.5 时成功率不低于 2/3 不会熔断
+	for i := 0; i < 100; i++ {
+		if i%3 == 0 {
+			b.markFailure()
+		} else {
+			b.markSuccess()
+		}
+		clock.Advance(time.Millisecond)
+	}
+	if _, _, dropRatio := b.metrics(); dropRatio != 0 {
+		t.Fatalf("dropRatio = %v, want 0", dropRatio)
+	}
+	for i := 0; i < 100; i++ {
+		if err := b.accept(); err != nil {
+			t.Fatalf("accept() = %v, want nil", err)
+		}
+	}
+}
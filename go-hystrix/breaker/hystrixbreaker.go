@@ -0,0 +1,316 @@
+package breaker
+
+import (
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFailureRatio     = 0.5
+	defaultMinRequests      = 20
+	defaultSleepWindow      = time.Second * 5
+	defaultHalfOpenRequests = 1
+)
+
+// State 熔断器状态
+type State int32
+
+const (
+	// StateClosed 关闭状态，请求正常通过
+	StateClosed State = iota
+	// StateOpen 打开状态，请求全部被拒绝
+	StateOpen
+	// StateHalfOpen 半开状态，只允许有限的探测请求通过
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// HystrixConfig 三态熔断器配置，零值字段使用默认值
+type HystrixConfig struct {
+	// 连续失败次数达到该值时熔断，0 表示不按连续失败熔断
+	ConsecutiveFailures int
+	// 窗口内失败率达到该值时熔断，取值 (0, 1]
+	// ConsecutiveFailures 与 FailureRatio 都为 0 时默认按 50% 失败率熔断
+	FailureRatio float64
+	// 按失败率熔断时，窗口内最少需要的请求数
+	MinRequests int64
+	// 熔断后经过多长时间进入半开状态
+	SleepWindow time.Duration
+	// 半开状态下允许通过的探测请求数，全部成功后关闭熔断器
+	HalfOpenRequests int
+}
+
+// hystrixBreaker Hystrix 风格的三态熔断器
+// closed -> open: 连续失败次数或窗口内失败率达到阈值
+// open -> half-open: 经过 SleepWindow
+// half-open -> closed: HalfOpenRequests 个探测请求全部成功
+// half-open -> open: 任一探测请求失败
+type hystrixBreaker struct {
+	conf  HystrixConfig
+	clock timex.Clock
+	// 创建滑动窗口，状态切换时重置统计
+	newStat func() *collection.RollingWindow
+	// 状态变化回调，在释放锁之后调用
+	onStateChange func(from, to State)
+
+	lock  sync.Mutex
+	state State
+	// 每次状态切换递增，用于丢弃上一个状态中放行的请求结果
+	generation uint64
+	// 关闭状态下的统计
+	stat        *collection.RollingWindow
+	consecutive int
+	// 进入打开状态的时间
+	openedAt time.Duration
+	// 半开状态下已放行和已成功的探测请求数
+	probes    int
+	successes int
+}
+
+func newHystrixBreaker(conf HystrixConfig, newStat func() *collection.RollingWindow,
+	clock timex.Clock, onStateChange func(from, to State)) *hystrixBreaker {
+	if conf.ConsecutiveFailures <= 0 && conf.FailureRatio <= 0 {
+		conf.FailureRatio = defaultFailureRatio
+	}
+	if conf.MinRequests <= 0 {
+		conf.MinRequests = defaultMinRequests
+	}
+	if conf.SleepWindow <= 0 {
+		conf.SleepWindow = defaultSleepWindow
+	}
+	if conf.HalfOpenRequests <= 0 {
+		conf.HalfOpenRequests = defaultHalfOpenRequests
+	}
+
+	return &hystrixBreaker{
+		conf:          conf,
+		clock:         clock,
+		newStat:       newStat,
+		onStateChange: onStateChange,
+		stat:          newStat(),
+	}
+}
+
+func (b *hystrixBreaker) allow() (internalPromise, error) {
+	generation, err := b.accept()
+	if err != nil {
+		return nil, err
+	}
+	return hystrixPromise{
+		b:          b,
+		generation: generation,
+	}, nil
+}
+
+func (b *hystrixBreaker) doReq(req func() error, fallback func(err error) error, acceptable Acceptable) error {
+	generation, err := b.accept()
+	if err != nil {
+		if fallback != nil {
+			return fallback(err)
+		}
+
+		return err
+	}
+
+	defer func() {
+		if e := recover(); e != nil {
+			b.markFailure(generation)
+			panic(e)
+		}
+	}()
+
+	err = req()
+	if acceptable(err) {
+		b.markSuccess(generation)
+	} else {
+		b.markFailure(generation)
+	}
+	return err
+}
+
+// accept 判断当前状态是否放行请求，返回放行时所处的代
+func (b *hystrixBreaker) accept() (uint64, error) {
+	b.lock.Lock()
+	defer b.unlock(b.state)
+
+	switch b.currentState() {
+	case StateOpen:
+		return 0, ErrServiceUnavailable
+	case StateHalfOpen:
+		if b.probes >= b.conf.HalfOpenRequests {
+			return 0, ErrServiceUnavailable
+		}
+		b.probes++
+	}
+
+	return b.generation, nil
+}
+
+func (b *hystrixBreaker) markSuccess(generation uint64) {
+	b.lock.Lock()
+	defer b.unlock(b.state)
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		b.consecutive = 0
+		b.stat.Add(1)
+	case StateHalfOpen:
+		b.successes++
+		if b.successes >= b.conf.HalfOpenRequests {
+			b.setState(StateClosed)
+		}
+	}
+}
+
+func (b *hystrixBreaker) markFailure(generation uint64) {
+	b.lock.Lock()
+	defer b.unlock(b.state)
+
+	if generation != b.generation {
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		b.consecutive++
+		b.stat.Add(0)
+		if b.shouldOpen() {
+			b.setState(StateOpen)
+		}
+	case StateHalfOpen:
+		b.setState(StateOpen)
+	}
+}
+
+// markIgnored 放弃请求结果，半开状态下归还占用的探测名额
+func (b *hystrixBreaker) markIgnored(generation uint64) {
+	b.lock.Lock()
+	defer b.unlock(b.state)
+
+	if generation != b.generation {
+		return
+	}
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// currentState 返回当前状态，打开状态超过 SleepWindow 时切换到半开状态
+func (b *hystrixBreaker) currentState() State {
+	if b.state == StateOpen && b.clock.Since(b.openedAt) >= b.conf.SleepWindow {
+		b.setState(StateHalfOpen)
+	}
+	return b.state
+}
+
+// unlock 释放锁，状态相对加锁时的 from 发生变化则触发回调
+// 回调在锁外执行，避免监听器中再调用熔断器导致死锁
+func (b *hystrixBreaker) unlock(from State) {
+	to := b.state
+	b.lock.Unlock()
+
+	if from != to {
+		b.onStateChange(from, to)
+	}
+}
+
+func (b *hystrixBreaker) metrics() (accepts, total int64, dropRatio float64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	// 非关闭状态下除探测请求外全部拒绝
+	accepts, total = b.history()
+	if b.state != StateClosed {
+		dropRatio = 1
+	}
+	return
+}
+
+func (b *hystrixBreaker) snapshot() ([]byte, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.stat.Snapshot()
+}
+
+// restore 只恢复关闭状态下的统计，熔断状态从关闭开始，由后续请求重新判断
+func (b *hystrixBreaker) restore(data []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.stat.Restore(data)
+}
+
+func (b *hystrixBreaker) shouldOpen() bool {
+	if b.conf.ConsecutiveFailures > 0 && b.consecutive >= b.conf.ConsecutiveFailures {
+		return true
+	}
+	if b.conf.FailureRatio <= 0 {
+		return false
+	}
+
+	accepts, total := b.history()
+	if total < b.conf.MinRequests {
+		return false
+	}
+	return float64(total-accepts)/float64(total) >= b.conf.FailureRatio
+}
+
+func (b *hystrixBreaker) history() (accepts, total int64) {
+	b.stat.Reduce(func(b *collection.Bucket) {
+		accepts += int64(b.Sum)
+		total += b.Count
+	})
+	return
+}
+
+func (b *hystrixBreaker) setState(state State) {
+	b.state = state
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+
+	switch state {
+	case StateClosed:
+		b.consecutive = 0
+		b.stat = b.newStat()
+	case StateOpen:
+		b.openedAt = b.clock.Now()
+	}
+}
+
+type hystrixPromise struct {
+	b          *hystrixBreaker
+	generation uint64
+}
+
+func (p hystrixPromise) Accept() {
+	p.b.markSuccess(p.generation)
+}
+
+func (p hystrixPromise) Reject() {
+	p.b.markFailure(p.generation)
+}
+
+func (p hystrixPromise) Ignore() {
+	p.b.markIgnored(p.generation)
+}
@@ -0,0 +1,42 @@
+package breaker
+
+// Stat 事件发生时熔断器的统计数据
+type Stat struct {
+	// 熔断器名称
+	Name string
+	// 滑动窗口内成功的请求数
+	Accepts int64
+	// 滑动窗口内的总请求数
+	Total int64
+	// 当前的熔断概率，取值 [0, 1]
+	DropRatio float64
+}
+
+// Listener 熔断器事件监听器
+// Listener 的方法在请求所在的 goroutine 中同步调用，实现方不应阻塞
+type Listener interface {
+	// OnStateChange is called when the Breaker changes its state.
+	OnStateChange(stat Stat, from, to State)
+	// OnReject is called when a request is dropped by the Breaker,
+	// err is ErrServiceUnavailable or ErrMaxConcurrency.
+	OnReject(stat Stat, err error)
+	// OnSuccess is called when a request is accepted as a successful call.
+	OnSuccess(stat Stat)
+	// OnFailure is called when a request is failed with the given reason.
+	OnFailure(stat Stat, reason string)
+}
+
+// NopListener 空实现，嵌入后只需实现关心的事件
+type NopListener struct{}
+
+// OnStateChange implements Listener.
+func (NopListener) OnStateChange(Stat, State, State) {}
+
+// OnReject implements Listener.
+func (NopListener) OnReject(Stat, error) {}
+
+// OnSuccess implements Listener.
+func (NopListener) OnSuccess(Stat) {}
+
+// OnFailure implements Listener.
+func (NopListener) OnFailure(Stat, string) {}
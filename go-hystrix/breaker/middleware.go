@@ -0,0 +1,113 @@
+package breaker
+
+import "net/http"
+
+// Middleware returns an http middleware that protects the wrapped handler with breakers from the registry,
+// so 5xx responses of one route don't shed the others if next is an *http.ServeMux: each registered pattern
+// gets its own breaker named "name method pattern", and requests matching no pattern go without a breaker.
+// Other handlers are protected as a whole by the breaker named name, use MiddlewareWithKey to key them by route.
+// Panics and 5xx responses are counted as failures, rejected requests get 503 Service Unavailable.
+// It works on the http.Handler level, frameworks with their own middleware types can only be wrapped
+// as a whole, for example the gin.Engine or framework.Core passed to http.Server.
+func Middleware(name string, opts ...Option) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		key := func(r *http.Request) string {
+			return name
+		}
+		if mux, ok := next.(*http.ServeMux); ok {
+			key = func(r *http.Request) string {
+				if _, pattern := mux.Handler(r); pattern != "" {
+					return name + " " + r.Method + " " + pattern
+				}
+				return ""
+			}
+		}
+
+		return MiddlewareWithKey(key, opts...)(next)
+	}
+}
+
+// MiddlewareWithKey is the same as Middleware, but the breaker of each request is named key(r).
+// The breakers are never removed from the registry, so key must map requests to a bounded set of names,
+// such as the route templates like "GET /user/:id" instead of the raw paths, or return "" to skip the breaker.
+// The breaker of a key is created by the first response that is not 404 Not Found,
+// so scanning unknown urls doesn't create breakers.
+func MiddlewareWithKey(key func(r *http.Request) string, opts ...Option) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := key(r)
+			if name == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			b, ok := lookupBreaker(name)
+			if !ok {
+				serveFirst(name, next, recorder, r, opts)
+				return
+			}
+
+			err := b.Do(func() error {
+				return serve(next, recorder, r)
+			})
+			if err == ErrServiceUnavailable || err == ErrMaxConcurrency {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			}
+		})
+	}
+}
+
+// serveFirst 处理 key 还没有熔断器时的请求，响应不是 404 时创建熔断器并记录结果
+func serveFirst(name string, next http.Handler, recorder *statusRecorder, r *http.Request, opts []Option) {
+	defer func() {
+		if e := recover(); e != nil {
+			// 由熔断器记录失败后继续 panic
+			GetBreaker(name, opts...).Do(func() error {
+				panic(e)
+			})
+		}
+	}()
+
+	err := serve(next, recorder, r)
+	if recorder.status != http.StatusNotFound {
+		GetBreaker(name, opts...).Do(func() error {
+			return err
+		})
+	}
+}
+
+func serve(next http.Handler, recorder *statusRecorder, r *http.Request) error {
+	next.ServeHTTP(recorder, r)
+	if recorder.status >= http.StatusInternalServerError {
+		return statusError(recorder.status)
+	}
+	return nil
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter supports it.
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,118 @@
+package breaker
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewarePerRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/healthy", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Middleware(t.Name())(mux)
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	var dropped int
+	for i := 0; i < 100; i++ {
+		if serve("/broken") == http.StatusServiceUnavailable {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Fatal("breaker of /broken never tripped")
+	}
+
+	// /broken 熔断不影响其他路由
+	for i := 0; i < 100; i++ {
+		if code := serve("/healthy"); code != http.StatusOK {
+			t.Fatalf("GET /healthy = %d, want %d", code, http.StatusOK)
+		}
+	}
+}
+
+func TestMiddlewareNotFound(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/user/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Middleware(t.Name())(mux)
+
+	// 扫描不存在的路径不创建熔断器，同一模式的路径共用一个熔断器
+	for i := 0; i < 100; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/unknown/%d", i), nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("GET /unknown/%d = %d, want %d", i, w.Code, http.StatusNotFound)
+		}
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/user/%d", i), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /user/%d = %d, want %d", i, w.Code, http.StatusOK)
+		}
+	}
+
+	if names := breakerNames(t.Name()); !reflect.DeepEqual(names, []string{t.Name() + " GET /user/"}) {
+		t.Fatalf("breakers = %v, want only the /user/ pattern", names)
+	}
+}
+
+func TestMiddlewareWithKeyNotFound(t *testing.T) {
+	handler := MiddlewareWithKey(func(r *http.Request) string {
+		return t.Name() + " " + r.URL.Path
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/known" {
+			http.NotFound(w, r)
+		}
+	}))
+
+	for _, path := range []string{"/a", "/b", "/known", "/c", "/known"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if names := breakerNames(t.Name()); !reflect.DeepEqual(names, []string{t.Name() + " /known"}) {
+		t.Fatalf("breakers = %v, want only /known", names)
+	}
+}
+
+func TestMiddlewareFirstRequestPanics(t *testing.T) {
+	handler := Middleware(t.Name())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic of the handler is swallowed")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+
+	m := GetBreaker(t.Name()).(*circuitBreaker).metrics()
+	if m.windowTotal != 1 || m.windowAccepts != 0 {
+		t.Fatalf("window = (%d accepts, %d total), want the panic counted as a failure", m.windowAccepts, m.windowTotal)
+	}
+}
+
+// breakerNames 返回注册表中以 prefix 开头的熔断器名
+func breakerNames(prefix string) []string {
+	var names []string
+	for _, name := range Names() {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,88 @@
+package breaker
+
+import (
+	"examples/go-hystrix/timex"
+	"time"
+)
+
+// WithName customizes the name of the Breaker.
+func WithName(name string) Option {
+	return func(b *circuitBreaker) {
+		b.name = name
+	}
+}
+
+// WithWindow customizes the duration of the rolling window used to collect statistics.
+func WithWindow(window time.Duration) Option {
+	return func(b *circuitBreaker) {
+		b.window = window
+	}
+}
+
+// WithBuckets customizes the number of buckets in the rolling window.
+func WithBuckets(buckets int) Option {
+	return func(b *circuitBreaker) {
+		b.buckets = buckets
+	}
+}
+
+// WithK customizes the sensitivity of the google breaker, the smaller the more aggressive.
+// k must not be less than 1, otherwise requests will be dropped even if all of them succeed.
+func WithK(k float64) Option {
+	return func(b *circuitBreaker) {
+		b.k = k
+	}
+}
+
+// WithProtection customizes the number of requests in the window that are never dropped.
+func WithProtection(protection int64) Option {
+	return func(b *circuitBreaker) {
+		b.protection = protection
+	}
+}
+
+// WithHystrix uses the classic closed/open/half-open state machine instead of
+// the default google SRE adaptive throttling.
+func WithHystrix(conf HystrixConfig) Option {
+	return func(b *circuitBreaker) {
+		b.hystrixConf = &conf
+	}
+}
+
+// WithListener adds a Listener to receive the events of the Breaker.
+func WithListener(listener Listener) Option {
+	return func(b *circuitBreaker) {
+		b.listeners = append(b.listeners, listener)
+	}
+}
+
+// WithTimeout customizes the execution timeout of the DoCtx family methods.
+// The request is cancelled and counted as a failure with context.DeadlineExceeded after timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(b *circuitBreaker) {
+		b.timeout = timeout
+	}
+}
+
+// WithMaxConcurrency limits the number of in-flight requests of the Breaker,
+// requests beyond the limit are rejected with ErrMaxConcurrency.
+// A DoCtx request that has timed out or been cancelled holds its slot until it really returns.
+func WithMaxConcurrency(maxConcurrency int) Option {
+	return func(b *circuitBreaker) {
+		b.maxConcurrency = maxConcurrency
+	}
+}
+
+// WithErrorHistory customizes the number of recent errors kept by the Breaker.
+func WithErrorHistory(size int) Option {
+	return func(b *circuitBreaker) {
+		b.errorHistory = size
+	}
+}
+
+// WithClock customizes the clock of the Breaker, mostly used in tests.
+func WithClock(clock timex.Clock) Option {
+	return func(b *circuitBreaker) {
+		b.clock = clock
+	}
+}
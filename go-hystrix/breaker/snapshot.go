@@ -0,0 +1,99 @@
+package breaker
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tal-tech/go-zero/core/errorx"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// SaveSnapshot writes the snapshot of b to the file at path.
+func SaveSnapshot(b Breaker, path string) error {
+	data, err := b.Snapshot()
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// LoadSnapshot restores b from the snapshot file at path.
+func LoadSnapshot(b Breaker, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return b.Restore(data)
+}
+
+// SaveSnapshots writes the snapshots of all the registered breakers to the file at path.
+func SaveSnapshots(path string) error {
+	snapshots := make(map[string]json.RawMessage)
+	for _, b := range Breakers() {
+		data, err := b.Snapshot()
+		if err != nil {
+			return err
+		}
+		snapshots[b.Name()] = data
+	}
+
+	data, err := json.Marshal(snapshots)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, data)
+}
+
+// LoadSnapshots restores the breakers in the snapshot file at path into the registry,
+// the breakers not exist are created with opts.
+// A breaker that fails to restore, such as with ErrIntervalMismatch after the window options changed,
+// doesn't stop the others, the errors of all such breakers are returned together.
+func LoadSnapshots(path string, opts ...Option) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var snapshots map[string]json.RawMessage
+	if err := json.Unmarshal(data, &snapshots); err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(snapshots))
+	for name := range snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var be errorx.BatchError
+	for _, name := range names {
+		if err := GetBreaker(name, opts...).Restore(snapshots[name]); err != nil {
+			be.Add(fmt.Errorf("restore breaker %q: %w", name, err))
+		}
+	}
+
+	return be.Err()
+}
+
+// writeFile 先写临时文件再重命名，避免进程退出时留下不完整的快照
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,128 @@
+package breaker
+
+import (
+	"errors"
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// resetRegistry 测试期间使用空的全局注册表，结束后恢复
+func resetRegistry(t *testing.T) {
+	lock.Lock()
+	saved := breakers
+	breakers = make(map[string]Breaker)
+	lock.Unlock()
+
+	t.Cleanup(func() {
+		lock.Lock()
+		breakers = saved
+		lock.Unlock()
+	})
+}
+
+// record 让 b 依次处理 accepts 个成功及 failures 个失败请求
+func record(b Breaker, accepts, failures int) {
+	for i := 0; i < accepts; i++ {
+		b.Do(func() error {
+			return nil
+		})
+	}
+	for i := 0; i < failures; i++ {
+		b.Do(func() error {
+			return errDownstream
+		})
+	}
+}
+
+func windowOf(b Breaker) (accepts, total int64) {
+	m := b.(*circuitBreaker).metrics()
+	return m.windowAccepts, m.windowTotal
+}
+
+func TestSaveLoadSnapshot(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	path := filepath.Join(t.TempDir(), "breaker.json")
+	b := NewBreaker(WithClock(clock))
+	record(b, 3, 2)
+	if err := SaveSnapshot(b, path); err != nil {
+		t.Fatal(err)
+	}
+
+	// 重启后 1s 恢复，统计仍在窗口内
+	clock.Advance(time.Second)
+	restored := NewBreaker(WithClock(clock))
+	if err := LoadSnapshot(restored, path); err != nil {
+		t.Fatal(err)
+	}
+	if accepts, total := windowOf(restored); accepts != 3 || total != 5 {
+		t.Fatalf("window = (%d, %d), want (3, 5)", accepts, total)
+	}
+
+	// 快照的统计过期后不再恢复
+	clock.Advance(defaultWindow)
+	expired := NewBreaker(WithClock(clock))
+	if err := LoadSnapshot(expired, path); err != nil {
+		t.Fatal(err)
+	}
+	if _, total := windowOf(expired); total != 0 {
+		t.Fatalf("window total = %d, want 0 after the snapshot expires", total)
+	}
+
+	if err := LoadSnapshot(expired, filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("LoadSnapshot() of missing file = nil, want error")
+	}
+}
+
+func TestSaveLoadSnapshots(t *testing.T) {
+	resetRegistry(t)
+	clock := timex.NewFakeClock(time.Now())
+	path := filepath.Join(t.TempDir(), "breakers.json")
+	record(GetBreaker("a", WithClock(clock)), 1, 1)
+	record(GetBreaker("b", WithClock(clock)), 2, 0)
+	if err := SaveSnapshots(path); err != nil {
+		t.Fatal(err)
+	}
+
+	resetRegistry(t)
+	if err := LoadSnapshots(path, WithClock(clock)); err != nil {
+		t.Fatal(err)
+	}
+	if accepts, total := windowOf(GetBreaker("a")); accepts != 1 || total != 2 {
+		t.Fatalf("window of a = (%d, %d), want (1, 2)", accepts, total)
+	}
+	if accepts, total := windowOf(GetBreaker("b")); accepts != 2 || total != 2 {
+		t.Fatalf("window of b = (%d, %d), want (2, 2)", accepts, total)
+	}
+}
+
+func TestLoadSnapshotsPartialFailure(t *testing.T) {
+	resetRegistry(t)
+	clock := timex.NewFakeClock(time.Now())
+	path := filepath.Join(t.TempDir(), "breakers.json")
+	record(GetBreaker("a", WithClock(clock)), 1, 1)
+	record(GetBreaker("b", WithClock(clock)), 2, 0)
+	record(GetBreaker("c", WithClock(clock)), 0, 3)
+	if err := SaveSnapshots(path); err != nil {
+		t.Fatal(err)
+	}
+
+	// a 的窗口配置变了，不能恢复，但不影响 b 和 c
+	resetRegistry(t)
+	GetBreaker("a", WithClock(clock), WithWindow(defaultWindow*2))
+	err := LoadSnapshots(path, WithClock(clock))
+	if !errors.Is(err, collection.ErrIntervalMismatch) {
+		t.Fatalf("LoadSnapshots() = %v, want %v", err, collection.ErrIntervalMismatch)
+	}
+	if _, total := windowOf(GetBreaker("a")); total != 0 {
+		t.Fatalf("window total of a = %d, want 0", total)
+	}
+	if accepts, total := windowOf(GetBreaker("b")); accepts != 2 || total != 2 {
+		t.Fatalf("window of b = (%d, %d), want (2, 2)", accepts, total)
+	}
+	if accepts, total := windowOf(GetBreaker("c")); accepts != 0 || total != 3 {
+		t.Fatalf("window of c = (%d, %d), want (0, 3)", accepts, total)
+	}
+}
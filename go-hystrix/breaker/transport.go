@@ -0,0 +1,62 @@
+package breaker
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Transport is an http.RoundTripper that protects each host with a breaker from the registry,
+// so that all the Transports share the same breaker per host.
+// Transport errors and 5xx responses are counted as failures, 4xx responses are not.
+type Transport struct {
+	// Base is the underlying RoundTripper, http.DefaultTransport is used if nil.
+	Base http.RoundTripper
+	// Options customize the breakers, only take effect when the breaker of the host is created.
+	Options []Option
+}
+
+// RoundTrip implements http.RoundTripper.
+// ErrServiceUnavailable or ErrMaxConcurrency is returned if the breaker rejects the request.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	err := GetBreaker(req.URL.Host, t.Options...).Do(func() error {
+		var err error
+		resp, err = t.base().RoundTrip(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return statusError(resp.StatusCode)
+		}
+		return nil
+	})
+
+	switch err.(type) {
+	case nil:
+		return resp, nil
+	case statusError:
+		// 5xx 只计入熔断器统计，响应照常返回给调用方
+		return resp, nil
+	default:
+		// RoundTripper 必须关闭请求 body，请求被熔断器拒绝时 Base 没有机会关闭
+		if (err == ErrServiceUnavailable || err == ErrMaxConcurrency) && req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+
+	return http.DefaultTransport
+}
+
+// statusError 5xx 响应对应的错误，用于让熔断器记为失败
+type statusError int
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("http status %d %s", int(e), http.StatusText(int(e)))
+}
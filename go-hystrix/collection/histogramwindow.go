@@ -0,0 +1,162 @@
+package collection
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// HistogramWindow 直方图滑动窗口
+// 每个桶按固定边界统计值的分布，可以查询窗口内的分位数
+type HistogramWindow struct {
+	*TypedRollingWindow[float64, *HistogramBucket]
+}
+
+// NewHistogramWindow returns a HistogramWindow with size buckets, each lasts interval.
+// bounds are the upper bounds of the distribution counters, must be sorted in increasing order,
+// values greater than the last bound are counted in the overflow counter.
+func NewHistogramWindow(size int, interval time.Duration, bounds []float64,
+	opts ...RollingWindowOption) *HistogramWindow {
+	if len(bounds) == 0 {
+		panic("bounds must not be empty")
+	}
+	if !sort.Float64sAreSorted(bounds) {
+		panic("bounds must be sorted in increasing order")
+	}
+
+	bounds = append([]float64(nil), bounds...)
+	return &HistogramWindow{
+		TypedRollingWindow: NewTypedRollingWindow[float64](size, interval, func() *HistogramBucket {
+			return NewHistogramBucket(bounds)
+		}, opts...),
+	}
+}
+
+// LinearBounds returns count bounds starting from start, each differs width from the previous one.
+func LinearBounds(start, width float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start + width*float64(i)
+	}
+	return bounds
+}
+
+// ExponentialBounds returns count bounds starting from start, each is factor times the previous one.
+func ExponentialBounds(start, factor float64, count int) []float64 {
+	bounds := make([]float64, count)
+	for i := range bounds {
+		bounds[i] = start
+		start *= factor
+	}
+	return bounds
+}
+
+// Percentile returns the p-th percentile of the values in the live window, p is in [0, 100].
+// The value is linearly interpolated within the bucket it falls in,
+// 0 is returned if there are no values in the window.
+func (hw *HistogramWindow) Percentile(p float64) float64 {
+	return hw.Merge().Percentile(p)
+}
+
+// HistogramBucket 存储一段时间范围内值的分布
+type HistogramBucket struct {
+	// Counts[i] 为落在 (bounds[i-1], bounds[i]] 内的值的个数，最后一个为超过所有边界的值的个数
+	Counts []int64
+	// 当前时间范围内值的和
+	Sum float64
+	// 当前桶内 Add 的次数
+	Count int64
+	// 当前时间范围内的最小值及最大值
+	Min float64
+	Max float64
+	// 分布统计的上边界，升序，同一个窗口的桶共享
+	bounds []float64
+}
+
+// NewHistogramBucket returns an empty HistogramBucket with given bounds.
+func NewHistogramBucket(bounds []float64) *HistogramBucket {
+	return &HistogramBucket{
+		Counts: make([]int64, len(bounds)+1),
+		bounds: bounds,
+	}
+}
+
+// Add adds v to the bucket.
+func (b *HistogramBucket) Add(v float64) {
+	if b.Count == 0 || v < b.Min {
+		b.Min = v
+	}
+	if b.Count == 0 || v > b.Max {
+		b.Max = v
+	}
+	// 第一个大于等于 v 的上边界
+	b.Counts[sort.SearchFloat64s(b.bounds, v)]++
+	b.Sum += v
+	b.Count++
+}
+
+// Merge merges other into the bucket, both must have the same bounds.
+func (b *HistogramBucket) Merge(other *HistogramBucket) {
+	if other.Count == 0 {
+		return
+	}
+
+	if b.Count == 0 || other.Min < b.Min {
+		b.Min = other.Min
+	}
+	if b.Count == 0 || other.Max > b.Max {
+		b.Max = other.Max
+	}
+	for i, c := range other.Counts {
+		b.Counts[i] += c
+	}
+	b.Sum += other.Sum
+	b.Count += other.Count
+}
+
+// compatible 快照中的桶与 b 的分布计数个数相同时才能合并
+func (b *HistogramBucket) compatible(other interface{}) bool {
+	o, ok := other.(*HistogramBucket)
+	return ok && len(o.Counts) == len(b.Counts)
+}
+
+// Reset clears the bucket.
+func (b *HistogramBucket) Reset() {
+	for i := range b.Counts {
+		b.Counts[i] = 0
+	}
+	b.Sum = 0
+	b.Count = 0
+	b.Min = 0
+	b.Max = 0
+}
+
+// Percentile returns the p-th percentile of the values in the bucket, p is in [0, 100].
+func (b *HistogramBucket) Percentile(p float64) float64 {
+	if b.Count == 0 {
+		return 0
+	}
+
+	p = math.Max(0, math.Min(100, p))
+	rank := p / 100 * float64(b.Count)
+	var cumulative int64
+	for i, c := range b.Counts {
+		if c == 0 || float64(cumulative+c) < rank {
+			cumulative += c
+			continue
+		}
+
+		// 分布区间 [lower, upper]，用实际的最小值和最大值收窄首尾区间
+		lower, upper := b.Min, b.Max
+		if i > 0 {
+			lower = math.Max(lower, b.bounds[i-1])
+		}
+		if i < len(b.bounds) {
+			upper = math.Min(upper, b.bounds[i])
+		}
+		fraction := (rank - float64(cumulative)) / float64(c)
+		return lower + (upper-lower)*fraction
+	}
+
+	return b.Max
+}
@@ -0,0 +1,79 @@
+package collection
+
+import (
+	"examples/go-hystrix/timex"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHistogramWindowPercentile(t *testing.T) {
+	tests := []struct {
+		name   string
+		bounds []float64
+		values []float64
+		p      float64
+		want   float64
+	}{
+		{"p0 is min", LinearBounds(10, 10, 10), rangeValues(1, 100), 0, 1},
+		{"p50", LinearBounds(10, 10, 10), rangeValues(1, 100), 50, 50},
+		{"p99", LinearBounds(10, 10, 10), rangeValues(1, 100), 99, 99},
+		{"p100 is max", LinearBounds(10, 10, 10), rangeValues(1, 100), 100, 100},
+		{"p over 100", LinearBounds(10, 10, 10), rangeValues(1, 100), 150, 100},
+		{"p below 0", LinearBounds(10, 10, 10), rangeValues(1, 100), -1, 1},
+		// 超过最后一个边界的值落在 (20, Max] 内插值
+		{"overflow p75", []float64{10, 20}, []float64{5, 15, 30, 50}, 75, 35},
+		{"overflow p100", []float64{10, 20}, []float64{5, 15, 30, 50}, 100, 50},
+		// 首个区间的下界收窄到 Min
+		{"first bucket clamped to min", []float64{10, 20}, []float64{5, 15, 30, 50}, 0, 5},
+		// 单个值时区间收窄为 [Min, Max]
+		{"single value", []float64{10, 100}, []float64{42}, 50, 42},
+		{"empty window", []float64{10, 100}, nil, 50, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := timex.NewFakeClock(time.Now())
+			hw := NewHistogramWindow(3, time.Second, test.bounds, WithClock(clock))
+			for _, v := range test.values {
+				hw.Add(v)
+			}
+			if got := hw.Percentile(test.p); math.Abs(got-test.want) > 1e-9 {
+				t.Fatalf("Percentile(%v) = %v, want %v", test.p, got, test.want)
+			}
+		})
+	}
+}
+
+func TestHistogramWindowBucketExpiry(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	hw := NewHistogramWindow(3, time.Second, LinearBounds(10, 10, 10), WithClock(clock))
+	hw.Add(100)
+	clock.Advance(time.Second)
+	hw.Add(10)
+	if got := hw.Percentile(100); got != 100 {
+		t.Fatalf("Percentile(100) = %v, want 100", got)
+	}
+
+	// 第一个桶过期
+	clock.Advance(2 * time.Second)
+	if got := hw.Percentile(100); got != 10 {
+		t.Fatalf("Percentile(100) = %v, want 10 after the first bucket expires", got)
+	}
+	if b := hw.Merge(); b.Count != 1 || b.Min != 10 || b.Max != 10 {
+		t.Fatalf("Merge() = {Count: %d, Min: %v, Max: %v}, want {1, 10, 10}", b.Count, b.Min, b.Max)
+	}
+
+	clock.Advance(3 * time.Second)
+	if got := hw.Percentile(50); got != 0 {
+		t.Fatalf("Percentile(50) = %v, want 0 on empty window", got)
+	}
+}
+
+// rangeValues 返回 [from, to] 内的整数
+func rangeValues(from, to int) []float64 {
+	values := make([]float64, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		values = append(values, float64(i))
+	}
+	return values
+}
@@ -6,14 +6,70 @@ import (
 	"time"
 )
 
-// RollingWindowOption 用于自定义 RollingWindow
-type RollingWindowOption func(rollingWindow *RollingWindow)
+// RollingWindowOption 用于自定义 RollingWindow，对所有类型的滑动窗口通用
+type RollingWindowOption func(conf *rollingWindowConf)
 
-type RollingWindow struct {
+// rollingWindowConf 滑动窗口的可选配置
+type rollingWindowConf struct {
+	// 汇总数据时，是否忽略当前正在写入桶的数据
+	ignoreCurrent bool
+	// 时钟，默认使用系统时间
+	clock timex.Clock
+}
+
+func newRollingWindowConf(opts []RollingWindowOption) rollingWindowConf {
+	conf := rollingWindowConf{
+		clock: timex.RealClock(),
+	}
+	for _, opt := range opts {
+		opt(&conf)
+	}
+	return conf
+}
+
+// IgnoreCurrentBucket lets the Reduce call ignore current bucket, which is still being written.
+func IgnoreCurrentBucket() RollingWindowOption {
+	return func(conf *rollingWindowConf) {
+		conf.ignoreCurrent = true
+	}
+}
+
+// WithClock customizes the clock of the RollingWindow, mostly used in tests.
+func WithClock(clock timex.Clock) RollingWindowOption {
+	return func(conf *rollingWindowConf) {
+		conf.clock = clock
+	}
+}
+
+// Aggregate 滑动窗口中桶的聚合类型，B 通常是实现了该接口的指针类型
+type Aggregate[V any, B any] interface {
+	// Add adds v to the bucket.
+	Add(v V)
+	// Reset clears the bucket when it's expired.
+	Reset()
+	// Merge merges other into the bucket.
+	Merge(other B)
+}
+
+// RollingWindow 统计 float64 值的和及次数的滑动窗口
+type RollingWindow = TypedRollingWindow[float64, *Bucket]
+
+// NewRollingWindow returns a RollingWindow with size buckets, each lasts interval.
+func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOption) *RollingWindow {
+	return NewTypedRollingWindow[float64](size, interval, func() *Bucket {
+		return new(Bucket)
+	}, opts...)
+}
+
+// TypedRollingWindow 桶类型由调用方定义的滑动窗口
+// V 为写入的值类型，B 为桶类型，桶负责聚合写入的值
+type TypedRollingWindow[V any, B Aggregate[V, B]] struct {
 	// 互斥锁
 	lock sync.Mutex
 	// 滑动窗口存储
-	win *window
+	win *window[V, B]
+	// 创建空桶
+	newBucket func() B
 	// 滑动窗口大小
 	size int
 	// 滑动窗口单元时间间隔
@@ -24,27 +80,32 @@ type RollingWindow struct {
 	ignoreCurrent bool
 	// 最后写入桶的时间(最后一个当前桶的开始时间)
 	lastTime time.Duration
+	// 时钟，默认使用系统时间
+	clock timex.Clock
 }
 
-func NewRollingWindow(size int, interval time.Duration, opts ...RollingWindowOption) *RollingWindow {
+// NewTypedRollingWindow returns a TypedRollingWindow with size buckets created by newBucket,
+// each lasts interval.
+func NewTypedRollingWindow[V any, B Aggregate[V, B]](size int, interval time.Duration, newBucket func() B,
+	opts ...RollingWindowOption) *TypedRollingWindow[V, B] {
 	if size < 1 {
 		panic("size must be greater than 0")
 	}
 
-	w := &RollingWindow{
-		size:     size,
-		interval: interval,
-		win:      newWindow(size),
-		lastTime: timex.Now(),
+	conf := newRollingWindowConf(opts)
+	return &TypedRollingWindow[V, B]{
+		size:          size,
+		interval:      interval,
+		win:           newWindow[V](size, newBucket),
+		newBucket:     newBucket,
+		ignoreCurrent: conf.ignoreCurrent,
+		lastTime:      conf.clock.Now(),
+		clock:         conf.clock,
 	}
-
-	for _, opt := range opts {
-		opt(w)
-	}
-	return w
 }
 
-func (rw *RollingWindow) Add(v float64) {
+// Add adds v to the current bucket.
+func (rw *TypedRollingWindow[V, B]) Add(v V) {
 	rw.lock.Lock()
 	defer rw.lock.Unlock()
 	// 更新当前时间写入 bucket 的偏移量
@@ -52,7 +113,8 @@ func (rw *RollingWindow) Add(v float64) {
 	rw.win.add(rw.offset, v)
 }
 
-func (rw *RollingWindow) Reduce(fn func(b *Bucket)) {
+// Reduce runs fn on all the live buckets in order, fn must not keep the bucket.
+func (rw *TypedRollingWindow[V, B]) Reduce(fn func(b B)) {
 	rw.lock.Lock()
 	defer rw.lock.Unlock()
 
@@ -70,7 +132,14 @@ func (rw *RollingWindow) Reduce(fn func(b *Bucket)) {
 	}
 }
 
-func (rw *RollingWindow) updateOffset() {
+// Merge returns a new bucket merged from all the live buckets.
+func (rw *TypedRollingWindow[V, B]) Merge() B {
+	result := rw.newBucket()
+	rw.Reduce(result.Merge)
+	return result
+}
+
+func (rw *TypedRollingWindow[V, B]) updateOffset() {
 	span := rw.span()
 	if span <= 0 {
 		return
@@ -82,14 +151,14 @@ func (rw *RollingWindow) updateOffset() {
 		rw.win.resetBucket((offset + i + 1) % rw.size)
 	}
 	rw.offset = (offset + span) % rw.size
-	now := timex.Now()
+	now := rw.clock.Now()
 	// current lastTime = now - (now - lastTime) % interval
 	rw.lastTime = now - (now-rw.lastTime)%rw.interval
 }
 
 // 获取过期的桶数：上一次更新的时间到当前时间经过的桶
-func (rw *RollingWindow) span() int {
-	offset := int(timex.Since(rw.lastTime) / rw.interval)
+func (rw *TypedRollingWindow[V, B]) span() int {
+	offset := int(rw.clock.Since(rw.lastTime) / rw.interval)
 	if offset >= 0 && offset < rw.size {
 		return offset
 	}
@@ -97,36 +166,36 @@ func (rw *RollingWindow) span() int {
 }
 
 // 时间窗口
-type window struct {
+type window[V any, B Aggregate[V, B]] struct {
 	// 每个桶代表一个时间间隔
-	buckets []*Bucket
+	buckets []B
 	// 窗口大小
 	size int
 }
 
-func newWindow(size int) *window {
-	buckets := make([]*Bucket, size)
+func newWindow[V any, B Aggregate[V, B]](size int, newBucket func() B) *window[V, B] {
+	buckets := make([]B, size)
 	for i := 0; i < size; i++ {
-		buckets[i] = new(Bucket)
+		buckets[i] = newBucket()
 	}
-	return &window{
+	return &window[V, B]{
 		buckets: buckets,
 		size:    size,
 	}
 }
 
-func (w *window) add(offset int, v float64) {
-	w.buckets[offset%w.size].add(v)
+func (w *window[V, B]) add(offset int, v V) {
+	w.buckets[offset%w.size].Add(v)
 }
 
-func (w *window) reduce(start int, count int, fn func(b *Bucket)) {
+func (w *window[V, B]) reduce(start int, count int, fn func(b B)) {
 	for i := 0; i < count; i++ {
 		fn(w.buckets[(start+i)%w.size])
 	}
 }
 
-func (w *window) resetBucket(offset int) {
-	w.buckets[offset%w.size].reset()
+func (w *window[V, B]) resetBucket(offset int) {
+	w.buckets[offset%w.size].Reset()
 }
 
 // Bucket 存储一段时间范围的统计值
@@ -137,12 +206,20 @@ type Bucket struct {
 	Count int64
 }
 
-func (b *Bucket) add(v float64) {
+// Add adds v to the bucket.
+func (b *Bucket) Add(v float64) {
 	b.Sum += v
 	b.Count++
 }
 
-func (b *Bucket) reset() {
+// Reset clears the bucket.
+func (b *Bucket) Reset() {
 	b.Sum = 0
 	b.Count = 0
 }
+
+// Merge merges other into the bucket.
+func (b *Bucket) Merge(other *Bucket) {
+	b.Sum += other.Sum
+	b.Count += other.Count
+}
@@ -0,0 +1,71 @@
+package collection
+
+import (
+	"examples/go-hystrix/timex"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testInterval = 100 * time.Millisecond
+
+func sums(rw *RollingWindow) []float64 {
+	var result []float64
+	rw.Reduce(func(b *Bucket) {
+		result = append(result, b.Sum)
+	})
+	return result
+}
+
+func TestRollingWindowBucketExpiry(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	rw := NewRollingWindow(3, testInterval, WithClock(clock))
+
+	tests := []struct {
+		advance time.Duration
+		add     float64
+		want    []float64
+	}{
+		{0, 1, []float64{0, 0, 1}},
+		{testInterval, 2, []float64{0, 1, 2}},
+		{testInterval, 3, []float64{1, 2, 3}},
+		// 第一个桶过期
+		{testInterval, 4, []float64{2, 3, 4}},
+		// 跳过一个桶，中间的桶为空
+		{2 * testInterval, 5, []float64{4, 0, 5}},
+		// 超过窗口大小，所有旧桶过期
+		{10 * testInterval, 6, []float64{0, 0, 6}},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		rw.Add(test.add)
+		if got := sums(rw); !reflect.DeepEqual(got, test.want) {
+			t.Fatalf("#%d: buckets = %v, want %v", i, got, test.want)
+		}
+	}
+
+	// 不写入时 Reduce 只统计未过期的桶
+	clock.Advance(2 * testInterval)
+	if got, want := sums(rw), []float64{6}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %v, want %v", got, want)
+	}
+	clock.Advance(testInterval)
+	if got := sums(rw); len(got) != 0 {
+		t.Fatalf("buckets = %v, want none", got)
+	}
+}
+
+func TestRollingWindowIgnoreCurrentBucket(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	rw := NewRollingWindow(3, testInterval, IgnoreCurrentBucket(), WithClock(clock))
+	rw.Add(1)
+	if got, want := sums(rw), []float64{0, 0}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %v, want %v", got, want)
+	}
+
+	clock.Advance(testInterval)
+	rw.Add(2)
+	if got, want := sums(rw), []float64{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("buckets = %v, want %v", got, want)
+	}
+}
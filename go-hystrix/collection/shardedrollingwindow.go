@@ -0,0 +1,174 @@
+package collection
+
+import (
+	"examples/go-hystrix/timex"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheLinePad 避免相邻分片落在同一个 cache line 上产生伪共享
+const cacheLinePad = 64 - 32
+
+// ShardedRollingWindow 分片的滑动窗口，Add 和 Reduce 的语义与 RollingWindow 相同
+// 每个桶拆成多个分片，每个分片有独立的锁，Add 只锁住其中一个分片，
+// 分片通过 sync.Pool 按 P 缓存选择，并发写入时不争用全局的锁或计数，
+// 适合大量 goroutine 共享同一个窗口的高并发场景
+// Reduce 逐个分片加锁汇总，不是整个窗口的原子快照
+type ShardedRollingWindow struct {
+	// 每个桶包含 shards 个分片
+	buckets []*shardedBucket
+	shards  int
+	// 缓存 *shardHint，同一个 P 上的 Add 大多复用同一个分片
+	hints sync.Pool
+	// 为新建的 shardHint 轮询分配分片，只在 sync.Pool 缓存失效时使用
+	next uint32
+	// 滑动窗口大小
+	size int
+	// 滑动窗口单元时间间隔
+	interval time.Duration
+	// 汇总数据时，是否忽略当前正在写入桶的数据
+	ignoreCurrent bool
+	// 窗口的开始时间，桶的编号为 (now - start) / interval
+	start time.Duration
+	// 最后写入的桶的编号，与 RollingWindow 的 lastTime 相同，决定 Reduce 汇总的桶
+	last int64
+	// 时钟，默认使用系统时间
+	clock timex.Clock
+}
+
+// NewShardedRollingWindow returns a ShardedRollingWindow, each bucket has shards shards,
+// GOMAXPROCS is used if shards is not positive. It accepts the same options as NewRollingWindow.
+func NewShardedRollingWindow(size int, interval time.Duration, shards int,
+	opts ...RollingWindowOption) *ShardedRollingWindow {
+	if size < 1 {
+		panic("size must be greater than 0")
+	}
+	if shards < 1 {
+		shards = runtime.GOMAXPROCS(0)
+	}
+
+	conf := newRollingWindowConf(opts)
+
+	buckets := make([]*shardedBucket, size)
+	for i := range buckets {
+		buckets[i] = newShardedBucket(shards)
+	}
+	rw := &ShardedRollingWindow{
+		buckets:       buckets,
+		shards:        shards,
+		size:          size,
+		interval:      interval,
+		ignoreCurrent: conf.ignoreCurrent,
+		start:         conf.clock.Now(),
+		clock:         conf.clock,
+	}
+	rw.hints.New = func() interface{} {
+		return &shardHint{
+			shard: int(atomic.AddUint32(&rw.next, 1) % uint32(rw.shards)),
+		}
+	}
+	return rw
+}
+
+// Add adds v to the current bucket.
+func (rw *ShardedRollingWindow) Add(v float64) {
+	idx := rw.index()
+	// 每个桶的时间内只有第一次 Add 需要写 last
+	for {
+		last := atomic.LoadInt64(&rw.last)
+		if idx <= last || atomic.CompareAndSwapInt64(&rw.last, last, idx) {
+			break
+		}
+	}
+
+	hint := rw.hints.Get().(*shardHint)
+	rw.buckets[idx%int64(rw.size)].cells[hint.shard].add(idx, v)
+	rw.hints.Put(hint)
+}
+
+// Reduce runs fn on all the live buckets, each bucket is the sum of its shards.
+func (rw *ShardedRollingWindow) Reduce(fn func(b *Bucket)) {
+	current := rw.index()
+	last := atomic.LoadInt64(&rw.last)
+	// 与 RollingWindow 相同：汇总 (current - size, last] 内的桶，
+	// 最后写入的桶就是当前桶时，按 ignoreCurrent 决定是否忽略
+	end := last
+	if last >= current && rw.ignoreCurrent {
+		end = current - 1
+	}
+	for idx := current - int64(rw.size) + 1; idx <= end; idx++ {
+		// 窗口创建前的桶为空
+		var b Bucket
+		if idx >= 0 {
+			b = rw.buckets[idx%int64(rw.size)].sum(idx)
+		}
+		fn(&b)
+	}
+}
+
+// index 返回当前时间所在桶的编号
+func (rw *ShardedRollingWindow) index() int64 {
+	return int64(rw.clock.Since(rw.start) / rw.interval)
+}
+
+// shardHint 缓存在 sync.Pool 中的分片编号
+type shardHint struct {
+	shard int
+}
+
+// shardedBucket 分片的桶
+type shardedBucket struct {
+	cells []shardCell
+}
+
+func newShardedBucket(shards int) *shardedBucket {
+	return &shardedBucket{
+		cells: make([]shardCell, shards),
+	}
+}
+
+// sum 汇总所有分片中编号为 idx 的数据，其他编号的数据已经过期
+func (b *shardedBucket) sum(idx int64) Bucket {
+	var result Bucket
+	for i := range b.cells {
+		sum, count := b.cells[i].load(idx)
+		result.Sum += sum
+		result.Count += count
+	}
+	return result
+}
+
+// shardCell 一个分片，记录所属桶的编号，编号变化时重置数据
+type shardCell struct {
+	lock  sync.Mutex
+	idx   int64
+	sum   float64
+	count int64
+	_     [cacheLinePad]byte
+}
+
+func (c *shardCell) add(idx int64, v float64) {
+	c.lock.Lock()
+	// 分片中是已经过期的桶，复用分片
+	// idx 更小时是写入前时钟已经跨桶的旧请求，与 RollingWindow 一样计入当前桶
+	if idx > c.idx {
+		c.idx = idx
+		c.sum = 0
+		c.count = 0
+	}
+	c.sum += v
+	c.count++
+	c.lock.Unlock()
+}
+
+func (c *shardCell) load(idx int64) (sum float64, count int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.idx != idx {
+		return 0, 0
+	}
+	return c.sum, c.count
+}
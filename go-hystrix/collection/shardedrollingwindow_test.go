@@ -0,0 +1,68 @@
+package collection
+
+import (
+	"examples/go-hystrix/timex"
+	"testing"
+	"time"
+)
+
+func TestShardedRollingWindowMatchesRollingWindow(t *testing.T) {
+	for _, ignoreCurrent := range []bool{false, true} {
+		clock := timex.NewFakeClock(time.Now())
+		opts := []RollingWindowOption{WithClock(clock)}
+		if ignoreCurrent {
+			opts = append(opts, IgnoreCurrentBucket())
+		}
+		rw := NewRollingWindow(4, time.Second, opts...)
+		srw := NewShardedRollingWindow(4, time.Second, 3, opts...)
+
+		steps := []time.Duration{0, 300, 800, 1000, 2500, 100, 4000, 900, 6000, 10, 1200}
+		for i, step := range steps {
+			clock.Advance(step * time.Millisecond)
+			for j := 0; j <= i%3; j++ {
+				rw.Add(float64(i))
+				srw.Add(float64(i))
+			}
+
+			want := reduceBuckets(rw.Reduce)
+			got := reduceBuckets(srw.Reduce)
+			if len(got) != len(want) {
+				t.Fatalf("ignoreCurrent=%v step %d: Reduce got %v, want %v", ignoreCurrent, i, got, want)
+			}
+			for k := range want {
+				if got[k] != want[k] {
+					t.Fatalf("ignoreCurrent=%v step %d: Reduce got %v, want %v", ignoreCurrent, i, got, want)
+				}
+			}
+		}
+	}
+}
+
+func reduceBuckets(reduce func(fn func(b *Bucket))) []Bucket {
+	var buckets []Bucket
+	reduce(func(b *Bucket) {
+		buckets = append(buckets, *b)
+	})
+	return buckets
+}
+
+// go test -bench Add -cpu=1,4,16 ./collection/
+func BenchmarkRollingWindowAdd(b *testing.B) {
+	rw := NewRollingWindow(40, time.Millisecond*250)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rw.Add(1)
+		}
+	})
+}
+
+func BenchmarkShardedRollingWindowAdd(b *testing.B) {
+	rw := NewShardedRollingWindow(40, time.Millisecond*250, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rw.Add(1)
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package collection
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrIntervalMismatch is returned by Restore when the snapshot has a different bucket interval.
+	ErrIntervalMismatch = errors.New("rolling window interval mismatch")
+	// ErrBucketMismatch is returned by Restore when the buckets in the snapshot can't be merged
+	// into the window, such as histogram buckets with different bounds.
+	ErrBucketMismatch = errors.New("rolling window bucket mismatch")
+)
+
+// compatibleBucket 桶可以实现该接口，Restore 合并前检查快照中的桶是否与窗口的桶兼容
+type compatibleBucket interface {
+	compatible(other interface{}) bool
+}
+
+// windowSnapshot 滑动窗口快照，桶的内容按 JSON 序列化
+type windowSnapshot struct {
+	// 桶的时间间隔
+	Interval time.Duration `json:"interval"`
+	// 存活的桶，由旧到新
+	Buckets []bucketSnapshot `json:"buckets"`
+}
+
+type bucketSnapshot struct {
+	// 桶的开始时间
+	Start time.Time `json:"start"`
+	// 桶的内容
+	Bucket json.RawMessage `json:"bucket"`
+}
+
+// Snapshot serializes the live buckets of the window with their start times to JSON.
+func (rw *TypedRollingWindow[V, B]) Snapshot() ([]byte, error) {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	span := rw.span()
+	diff := rw.size - span
+	snapshot := windowSnapshot{
+		Interval: rw.interval,
+		Buckets:  make([]bucketSnapshot, 0, diff),
+	}
+	if diff <= 0 {
+		return json.Marshal(snapshot)
+	}
+
+	// 当前桶(offset)的开始时间，往前每个桶依次早 interval
+	lastStart := rw.clock.Time().Add(-rw.clock.Since(rw.lastTime))
+	offset := (rw.offset + span + 1) % rw.size
+	for i := 0; i < diff; i++ {
+		data, err := json.Marshal(rw.win.buckets[(offset+i)%rw.size])
+		if err != nil {
+			return nil, err
+		}
+
+		snapshot.Buckets = append(snapshot.Buckets, bucketSnapshot{
+			Start:  lastStart.Add(-time.Duration(diff-1-i) * rw.interval),
+			Bucket: data,
+		})
+	}
+
+	return json.Marshal(snapshot)
+}
+
+// Restore merges the buckets in the snapshot into the window according to their start times.
+// Buckets that are already out of the window are discarded.
+func (rw *TypedRollingWindow[V, B]) Restore(data []byte) error {
+	var snapshot windowSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+	if snapshot.Interval != rw.interval {
+		return ErrIntervalMismatch
+	}
+
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	rw.updateOffset()
+	// 当前桶的开始时间
+	currentStart := rw.clock.Time().Add(-rw.clock.Since(rw.lastTime))
+	// 先解析并检查所有的桶，任一桶不兼容时不修改窗口
+	offsets := make([]int, 0, len(snapshot.Buckets))
+	buckets := make([]B, 0, len(snapshot.Buckets))
+	for _, bs := range snapshot.Buckets {
+		// 快照中的桶距离当前桶经过的桶数，按四舍五入对齐到当前窗口的桶
+		ago := int((currentStart.Sub(bs.Start) + rw.interval/2) / rw.interval)
+		if ago < 0 {
+			ago = 0
+		}
+		if ago >= rw.size {
+			continue
+		}
+
+		b := rw.newBucket()
+		if err := json.Unmarshal(bs.Bucket, &b); err != nil {
+			return err
+		}
+		offset := (rw.offset - ago + rw.size) % rw.size
+		if cb, ok := interface{}(rw.win.buckets[offset]).(compatibleBucket); ok && !cb.compatible(b) {
+			return ErrBucketMismatch
+		}
+
+		offsets = append(offsets, offset)
+		buckets = append(buckets, b)
+	}
+
+	for i, b := range buckets {
+		rw.win.buckets[offsets[i]].Merge(b)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package collection
+
+import (
+	"examples/go-hystrix/timex"
+	"testing"
+	"time"
+)
+
+func TestRollingWindowSnapshotRestore(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	rw := NewRollingWindow(4, time.Second, WithClock(clock))
+	rw.Add(1)
+	clock.Advance(time.Second)
+	rw.Add(2)
+	rw.Add(3)
+
+	data, err := rw.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 恢复时已经过去 2 个桶，第一个桶仍在窗口内
+	clock.Advance(2 * time.Second)
+	restored := NewRollingWindow(4, time.Second, WithClock(clock))
+	if err := restored.Restore(data); err != nil {
+		t.Fatal(err)
+	}
+	if b := restored.Merge(); b.Sum != 6 || b.Count != 3 {
+		t.Fatalf("Merge() = %+v, want {Sum:6 Count:3}", *b)
+	}
+
+	// 再过 1 个桶，第一个桶过期
+	clock.Advance(time.Second)
+	if b := restored.Merge(); b.Sum != 5 || b.Count != 2 {
+		t.Fatalf("Merge() = %+v, want {Sum:5 Count:2}", *b)
+	}
+}
+
+func TestRollingWindowRestoreIntervalMismatch(t *testing.T) {
+	data, err := NewRollingWindow(4, time.Second).Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := NewRollingWindow(4, time.Minute).Restore(data); err != ErrIntervalMismatch {
+		t.Fatalf("Restore() = %v, want %v", err, ErrIntervalMismatch)
+	}
+}
+
+func TestHistogramWindowRestoreBoundsMismatch(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	hw := NewHistogramWindow(4, time.Second, LinearBounds(10, 10, 5), WithClock(clock))
+	hw.Add(15)
+	hw.Add(100)
+	data, err := hw.Snapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧配置留下的快照边界更多，不能 panic
+	restored := NewHistogramWindow(4, time.Second, LinearBounds(10, 10, 3), WithClock(clock))
+	restored.Add(5)
+	if err := restored.Restore(data); err != ErrBucketMismatch {
+		t.Fatalf("Restore() = %v, want %v", err, ErrBucketMismatch)
+	}
+	if b := restored.Merge(); b.Count != 1 {
+		t.Fatalf("Merge().Count = %d after failed Restore, want 1", b.Count)
+	}
+}
@@ -0,0 +1,198 @@
+package hedge
+
+import (
+	"context"
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"time"
+)
+
+const (
+	defaultDelay    = time.Millisecond * 50
+	defaultMaxRatio = 0.1
+	ratioWindow     = time.Second * 10
+	ratioBuckets    = 40
+)
+
+// Option 用于自定义 Hedger
+type Option func(h *Hedger)
+
+// WithDelay customizes the fixed delay before the hedged request is issued, 50ms by default.
+// It's also used when the latency window has no data if WithPercentileDelay is given.
+func WithDelay(delay time.Duration) Option {
+	return func(h *Hedger) {
+		h.delay = delay
+	}
+}
+
+// WithPercentileDelay lets the delay before the hedged request be the p-th percentile of
+// the latencies in window, the latencies of successful requests are added to window in milliseconds,
+// so the bounds of window must be in milliseconds.
+func WithPercentileDelay(window *collection.HistogramWindow, p float64) Option {
+	return func(h *Hedger) {
+		h.latencies = window
+		h.percentile = p
+	}
+}
+
+// WithMaxRatio customizes the max ratio of hedged requests to requests in the last 10 seconds,
+// 0.1 by default.
+func WithMaxRatio(ratio float64) Option {
+	return func(h *Hedger) {
+		h.maxRatio = ratio
+	}
+}
+
+// WithClock customizes the clock of the hedge ratio window and the latencies, mostly used in tests.
+func WithClock(clock timex.Clock) Option {
+	return func(h *Hedger) {
+		h.clock = clock
+	}
+}
+
+// A Hedger issues a hedged (backup) request if the original request doesn't finish
+// within a delay, the first success wins and the other one is canceled through ctx.
+// The ratio of hedged requests is capped so that hedging can't double the load on the downstream.
+type Hedger struct {
+	// 固定的对冲延迟
+	delay time.Duration
+	// 成功请求的耗时(ms)，不为 nil 时按分位数计算对冲延迟
+	latencies  *collection.HistogramWindow
+	percentile float64
+	// 对冲请求数与请求数的最大比例
+	maxRatio float64
+	// 请求记为 0，对冲请求记为 1，Sum 为对冲请求数，Count - Sum 为请求数
+	stat  *collection.RollingWindow
+	clock timex.Clock
+	// 对冲延迟的定时器，返回到期通知及停止函数
+	after func(d time.Duration) (<-chan time.Time, func() bool)
+}
+
+// NewHedger returns a Hedger.
+func NewHedger(opts ...Option) *Hedger {
+	h := &Hedger{
+		delay:    defaultDelay,
+		maxRatio: defaultMaxRatio,
+		clock:    timex.RealClock(),
+		after:    after,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.delay <= 0 {
+		panic("delay must be greater than 0")
+	}
+	if h.percentile < 0 || h.percentile > 100 {
+		panic("percentile must be in [0, 100]")
+	}
+	if h.maxRatio < 0 {
+		panic("max ratio must not be negative")
+	}
+
+	h.stat = collection.NewRollingWindow(ratioBuckets, ratioWindow/ratioBuckets, collection.WithClock(h.clock))
+	return h
+}
+
+// Do calls fn with hedging, fn must return promptly when ctx is canceled.
+func (h *Hedger) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := Call(ctx, h, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+// Call calls fn with hedging and returns the result of the first success.
+// If the original request fails before the hedged request is issued, its error is returned
+// without hedging, otherwise the last error is returned if both fail.
+func Call[T any](ctx context.Context, h *Hedger, fn func(ctx context.Context) (T, error)) (T, error) {
+	// 返回时取消未完成的请求
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// 缓冲足够大，被取消的请求不会阻塞
+	results := make(chan result[T], 2)
+	call := func() {
+		start := h.clock.Now()
+		val, err := fn(ctx)
+		results <- result[T]{
+			val:     val,
+			err:     err,
+			latency: h.clock.Since(start),
+		}
+	}
+
+	h.stat.Add(0)
+	go call()
+	inflight := 1
+
+	hedge, stop := h.after(h.hedgeDelay())
+	defer stop()
+
+	var zero T
+	for {
+		select {
+		case <-hedge:
+			if h.allowHedge() {
+				inflight++
+				go call()
+			}
+		case r := <-results:
+			inflight--
+			if r.err == nil {
+				h.record(r.latency)
+				return r.val, nil
+			}
+			if inflight == 0 {
+				return zero, r.err
+			}
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		}
+	}
+}
+
+// hedgeDelay 有耗时统计时取分位数，否则使用固定延迟
+func (h *Hedger) hedgeDelay() time.Duration {
+	if h.latencies == nil {
+		return h.delay
+	}
+
+	if p := h.latencies.Percentile(h.percentile); p > 0 {
+		return time.Duration(p * float64(time.Millisecond))
+	}
+	return h.delay
+}
+
+// allowHedge 对冲比例未超过上限时记录一次对冲请求并返回 true
+func (h *Hedger) allowHedge() bool {
+	var hedges, total int64
+	h.stat.Reduce(func(b *collection.Bucket) {
+		hedges += int64(b.Sum)
+		total += b.Count
+	})
+
+	requests := total - hedges
+	if float64(hedges+1) > h.maxRatio*float64(requests) {
+		return false
+	}
+
+	h.stat.Add(1)
+	return true
+}
+
+func (h *Hedger) record(latency time.Duration) {
+	if h.latencies != nil {
+		h.latencies.Add(float64(latency) / float64(time.Millisecond))
+	}
+}
+
+func after(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+type result[T any] struct {
+	val     T
+	err     error
+	latency time.Duration
+}
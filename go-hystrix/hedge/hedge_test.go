@@ -0,0 +1,267 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	errFirst  = errors.New("first attempt failed")
+	errHedged = errors.New("hedged attempt failed")
+)
+
+// fakeTimer 由测试决定对冲延迟何时到期
+type fakeTimer struct {
+	delays chan time.Duration
+	fire   chan time.Time
+}
+
+func newFakeTimer(h *Hedger) *fakeTimer {
+	ft := &fakeTimer{
+		delays: make(chan time.Duration, 1),
+		fire:   make(chan time.Time),
+	}
+	h.after = func(d time.Duration) (<-chan time.Time, func() bool) {
+		ft.delays <- d
+		return ft.fire, func() bool {
+			return true
+		}
+	}
+	return ft
+}
+
+// expire 等待 Call 启动定时器后让它到期
+func (ft *fakeTimer) expire() time.Duration {
+	d := <-ft.delays
+	ft.fire <- time.Now()
+	return d
+}
+
+func TestCall(t *testing.T) {
+	tests := []struct {
+		name string
+		// 第 i 次调用的行为，started 在对冲请求开始时关闭
+		attempts []func(ctx context.Context, started <-chan struct{}) (string, error)
+		hedge    bool
+		want     string
+		wantErrs []error
+	}{
+		{
+			name: "original succeeds",
+			attempts: []func(context.Context, <-chan struct{}) (string, error){
+				func(context.Context, <-chan struct{}) (string, error) {
+					return "first", nil
+				},
+			},
+			want: "first",
+		},
+		{
+			name: "original fails before hedge",
+			attempts: []func(context.Context, <-chan struct{}) (string, error){
+				func(context.Context, <-chan struct{}) (string, error) {
+					return "", errFirst
+				},
+			},
+			wantErrs: []error{errFirst},
+		},
+		{
+			name: "hedge wins",
+			attempts: []func(context.Context, <-chan struct{}) (string, error){
+				func(ctx context.Context, _ <-chan struct{}) (string, error) {
+					<-ctx.Done()
+					return "", ctx.Err()
+				},
+				func(context.Context, <-chan struct{}) (string, error) {
+					return "hedged", nil
+				},
+			},
+			hedge: true,
+			want:  "hedged",
+		},
+		{
+			name: "original wins after hedge",
+			attempts: []func(context.Context, <-chan struct{}) (string, error){
+				func(_ context.Context, started <-chan struct{}) (string, error) {
+					<-started
+					return "first", nil
+				},
+				func(ctx context.Context, _ <-chan struct{}) (string, error) {
+					<-ctx.Done()
+					return "", ctx.Err()
+				},
+			},
+			hedge: true,
+			want:  "first",
+		},
+		{
+			name: "both fail",
+			attempts: []func(context.Context, <-chan struct{}) (string, error){
+				func(_ context.Context, started <-chan struct{}) (string, error) {
+					<-started
+					return "", errFirst
+				},
+				func(context.Context, <-chan struct{}) (string, error) {
+					return "", errHedged
+				},
+			},
+			hedge: true,
+			// 返回后结束的请求的错误
+			wantErrs: []error{errFirst, errHedged},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := NewHedger(WithMaxRatio(1), WithClock(timex.NewFakeClock(time.Now())))
+			ft := newFakeTimer(h)
+			started := make(chan struct{})
+			var calls int32
+			// 每次调用结束时 ctx 的错误
+			ctxErrs := make([]chan error, len(test.attempts))
+			for i := range ctxErrs {
+				ctxErrs[i] = make(chan error, 1)
+			}
+
+			type result struct {
+				val string
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				val, err := Call(context.Background(), h, func(ctx context.Context) (string, error) {
+					i := atomic.AddInt32(&calls, 1) - 1
+					if i == 1 {
+						close(started)
+					}
+					val, err := test.attempts[i](ctx, started)
+					// 等 Call 返回后再检查 ctx，确认输掉的请求被取消
+					go func() {
+						<-ctx.Done()
+						ctxErrs[i] <- ctx.Err()
+					}()
+					return val, err
+				})
+				done <- result{val, err}
+			}()
+
+			if test.hedge {
+				ft.expire()
+			}
+			r := <-done
+			if len(test.wantErrs) == 0 {
+				if r.err != nil || r.val != test.want {
+					t.Fatalf("Call() = (%q, %v), want (%q, nil)", r.val, r.err, test.want)
+				}
+			} else if !containsErr(test.wantErrs, r.err) {
+				t.Fatalf("Call() = %v, want one of %v", r.err, test.wantErrs)
+			}
+			if n := int(atomic.LoadInt32(&calls)); n != len(test.attempts) {
+				t.Fatalf("fn called %d times, want %d", n, len(test.attempts))
+			}
+			for i := range ctxErrs {
+				if err := <-ctxErrs[i]; err != context.Canceled {
+					t.Fatalf("ctx of attempt %d = %v after Call returns, want %v", i, err, context.Canceled)
+				}
+			}
+		})
+	}
+}
+
+func TestCallRatioCap(t *testing.T) {
+	h := NewHedger(WithClock(timex.NewFakeClock(time.Now())))
+	ft := newFakeTimer(h)
+	release := make(chan struct{})
+	var calls int32
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Do(context.Background(), func(ctx context.Context) error {
+			atomic.AddInt32(&calls, 1)
+			<-release
+			return nil
+		})
+	}()
+
+	// 默认比例 0.1 时，第一个请求不能对冲
+	ft.expire()
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Do() = %v, want nil", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("fn called %d times, want 1 when the ratio is exceeded", n)
+	}
+}
+
+func TestAllowHedge(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	h := NewHedger(WithMaxRatio(0.1), WithClock(clock))
+
+	tests := []struct {
+		// 判断前新增的请求数
+		requests int
+		allow    bool
+	}{
+		{9, false},
+		{1, true},
+		// 第二个对冲请求需要 20 个请求
+		{9, false},
+		{1, true},
+		{0, false},
+	}
+	for i, test := range tests {
+		for j := 0; j < test.requests; j++ {
+			h.stat.Add(0)
+		}
+		if allow := h.allowHedge(); allow != test.allow {
+			t.Fatalf("#%d: allowHedge() = %v, want %v", i, allow, test.allow)
+		}
+	}
+
+	// 窗口过去后请求数清零，不能对冲
+	clock.Advance(ratioWindow)
+	if h.allowHedge() {
+		t.Fatal("allowHedge() = true after the window passes, want false")
+	}
+}
+
+func TestPercentileDelay(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	latencies := collection.NewHistogramWindow(10, time.Second, collection.LinearBounds(10, 10, 20),
+		collection.WithClock(clock))
+	h := NewHedger(WithDelay(30*time.Millisecond), WithPercentileDelay(latencies, 50), WithClock(clock))
+	ft := newFakeTimer(h)
+
+	fn := func(ctx context.Context) error {
+		clock.Advance(80 * time.Millisecond)
+		return nil
+	}
+
+	// 没有耗时统计时使用固定延迟
+	if err := h.Do(context.Background(), fn); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-ft.delays; d != 30*time.Millisecond {
+		t.Fatalf("delay = %v, want 30ms without latencies", d)
+	}
+
+	// 成功请求的耗时记录后按分位数计算延迟
+	if err := h.Do(context.Background(), fn); err != nil {
+		t.Fatal(err)
+	}
+	if d := <-ft.delays; d != 80*time.Millisecond {
+		t.Fatalf("delay = %v, want the p50 latency 80ms", d)
+	}
+}
+
+func containsErr(errs []error, err error) bool {
+	for _, e := range errs {
+		if e == err {
+			return true
+		}
+	}
+	return false
+}
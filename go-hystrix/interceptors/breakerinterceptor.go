@@ -0,0 +1,130 @@
+package interceptors
+
+import (
+	"context"
+	"examples/go-hystrix/breaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"path"
+	"sync"
+)
+
+// UnaryClientBreakerInterceptor wraps each unary call in the breaker named target/method.
+// When the breaker drops the call, an error with codes.Unavailable is returned.
+func UnaryClientBreakerInterceptor(ctx context.Context, method string, req, reply interface{},
+	cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	breakerName := path.Join(cc.Target(), method)
+	return convertBreakerError(breaker.DoWithAcceptable(breakerName, func() error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}, acceptable))
+}
+
+// StreamClientBreakerInterceptor wraps each stream in the breaker named target/method.
+// The stream is reported to the breaker when it ends, that is RecvMsg returns an error (io.EOF means success),
+// or the response of a non-server-streaming call is received, so the caller must drain the stream.
+// When the breaker drops the call, an error with codes.Unavailable is returned.
+func StreamClientBreakerInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+	method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	breakerName := path.Join(cc.Target(), method)
+	promise, err := breaker.GetBreaker(breakerName).Allow()
+	if err != nil {
+		return nil, convertBreakerError(err)
+	}
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		reportStream(promise, err)
+		return nil, err
+	}
+
+	return &breakerClientStream{
+		ClientStream:  stream,
+		serverStreams: desc.ServerStreams,
+		promise:       promise,
+	}, nil
+}
+
+// UnaryServerBreakerInterceptor wraps each unary handler in the breaker named by the full method,
+// so that a failing handler is protected from being called over and over.
+func UnaryServerBreakerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	var resp interface{}
+	err := breaker.DoWithAcceptable(info.FullMethod, func() error {
+		var err error
+		resp, err = handler(ctx, req)
+		return err
+	}, acceptable)
+
+	return resp, convertBreakerError(err)
+}
+
+// StreamServerBreakerInterceptor wraps each stream handler in the breaker named by the full method.
+func StreamServerBreakerInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	return convertBreakerError(breaker.DoWithAcceptable(info.FullMethod, func() error {
+		return handler(srv, stream)
+	}, acceptable))
+}
+
+// breakerClientStream 在流结束时将最终的结果上报给熔断器
+// 流的错误通常在 RecvMsg 时才返回，只包装创建流无法统计到服务端错误
+type breakerClientStream struct {
+	grpc.ClientStream
+	// 服务端是否返回多个消息，否则收到一个响应后流即结束
+	serverStreams bool
+	promise       breaker.Promise
+	once          sync.Once
+}
+
+func (s *breakerClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err != nil || !s.serverStreams {
+		s.report(err)
+	}
+	return err
+}
+
+func (s *breakerClientStream) CloseSend() error {
+	err := s.ClientStream.CloseSend()
+	if err != nil {
+		s.report(err)
+	}
+	return err
+}
+
+// report 只上报一次
+func (s *breakerClientStream) report(err error) {
+	s.once.Do(func() {
+		reportStream(s.promise, err)
+	})
+}
+
+// reportStream 上报流的结果，io.EOF 表示流正常结束
+func reportStream(promise breaker.Promise, err error) {
+	if err == io.EOF || acceptable(err) {
+		promise.Accept()
+	} else {
+		promise.Reject(err.Error())
+	}
+}
+
+// acceptable 只有服务端的错误才计为失败，客户端参数错误、未找到等不影响熔断
+func acceptable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.ResourceExhausted:
+		return false
+	default:
+		return true
+	}
+}
+
+// convertBreakerError 熔断器拒绝的请求转换为 codes.Unavailable
+func convertBreakerError(err error) error {
+	if err == breaker.ErrServiceUnavailable || err == breaker.ErrMaxConcurrency {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+
+	return err
+}
@@ -0,0 +1,106 @@
+package interceptors
+
+import (
+	"context"
+	"examples/go-hystrix/breaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+)
+
+func dialBufconn(t *testing.T, handler grpc.StreamHandler) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnknownServiceHandler(handler))
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	cc, err := grpc.Dial(t.Name(), grpc.WithInsecure(),
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+		grpc.WithStreamInterceptor(StreamClientBreakerInterceptor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cc.Close()
+	})
+	return cc
+}
+
+func TestStreamClientBreakerInterceptorTrips(t *testing.T) {
+	cc := dialBufconn(t, func(interface{}, grpc.ServerStream) error {
+		return status.Error(codes.Unavailable, "downstream unavailable")
+	})
+	desc := &grpc.StreamDesc{ServerStreams: true}
+
+	var dropped int
+	for i := 0; i < 100; i++ {
+		stream, err := cc.NewStream(context.Background(), desc, "/test.Service/Stream")
+		if err != nil {
+			if status.Code(err) != codes.Unavailable {
+				t.Fatalf("NewStream() = %v", err)
+			}
+			dropped++
+			continue
+		}
+
+		// 服务端错误在 RecvMsg 时才返回
+		if err := stream.RecvMsg(new(emptypb.Empty)); status.Code(err) != codes.Unavailable {
+			t.Fatalf("RecvMsg() = %v, want codes.Unavailable", err)
+		}
+	}
+
+	if dropped == 0 {
+		t.Fatal("stream breaker never tripped")
+	}
+}
+
+// countingListener 统计熔断器上报的成功及失败次数
+type countingListener struct {
+	breaker.NopListener
+	successes int32
+	failures  int32
+}
+
+func (l *countingListener) OnSuccess(breaker.Stat) {
+	atomic.AddInt32(&l.successes, 1)
+}
+
+func (l *countingListener) OnFailure(breaker.Stat, string) {
+	atomic.AddInt32(&l.failures, 1)
+}
+
+func TestStreamClientBreakerInterceptorEOF(t *testing.T) {
+	cc := dialBufconn(t, func(interface{}, grpc.ServerStream) error {
+		return nil
+	})
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	method := "/test.Service/Stream"
+	// 先注册带监听器的熔断器，拦截器通过名字取到同一个熔断器
+	listener := new(countingListener)
+	breaker.GetBreaker(cc.Target()+method, breaker.WithListener(listener))
+
+	for i := 0; i < 100; i++ {
+		stream, err := cc.NewStream(context.Background(), desc, method)
+		if err != nil {
+			t.Fatalf("NewStream() = %v", err)
+		}
+		if err := stream.RecvMsg(new(emptypb.Empty)); err != io.EOF {
+			t.Fatalf("RecvMsg() = %v, want io.EOF", err)
+		}
+	}
+
+	if successes := atomic.LoadInt32(&listener.successes); successes != 100 {
+		t.Fatalf("OnSuccess called %d times, want 100", successes)
+	}
+	if failures := atomic.LoadInt32(&listener.failures); failures != 0 {
+		t.Fatalf("OnFailure called %d times, want 0", failures)
+	}
+}
@@ -0,0 +1,38 @@
+package interceptors
+
+import (
+	"context"
+	"examples/go-hystrix/load"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnarySheddingInterceptor returns a unary server interceptor that consults shedder before
+// handling the request. Overloaded requests are rejected with codes.ResourceExhausted, which
+// are counted as failures by the client breaker interceptors.
+func UnarySheddingInterceptor(shedder load.Shedder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (resp interface{}, err error) {
+		promise, err := shedder.Allow()
+		if err != nil {
+			return nil, status.Error(codes.ResourceExhausted, err.Error())
+		}
+
+		// handler panic 时也要结束 promise，否则 flying 永远不会减少
+		defer func() {
+			if e := recover(); e != nil {
+				promise.Fail()
+				panic(e)
+			}
+
+			if acceptable(err) {
+				promise.Pass()
+			} else {
+				promise.Fail()
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,39 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/load"
+	"google.golang.org/grpc"
+	"testing"
+)
+
+func TestUnarySheddingInterceptorPanic(t *testing.T) {
+	shedder := load.NewAdaptiveShedder()
+	interceptor := UnarySheddingInterceptor(shedder)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic of the handler is swallowed")
+			}
+		}()
+		interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			panic("boom")
+		})
+	}()
+
+	errHandler := errors.New("handler error")
+	if _, err := interceptor(context.Background(), nil, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, errHandler
+		}); err != errHandler {
+		t.Fatalf("interceptor() = %v, want %v", err, errHandler)
+	}
+
+	stat := shedder.Stat()
+	if stat.Pass != 2 || stat.Flying != 0 {
+		t.Fatalf("Stat() = %+v, want 2 pass and nothing flying", stat)
+	}
+}
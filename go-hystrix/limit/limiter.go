@@ -0,0 +1,109 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/timex"
+	"sync"
+	"time"
+)
+
+// ErrLimitExceeded is returned by Wait when the request can't be admitted before ctx's deadline.
+var ErrLimitExceeded = errors.New("rate limit exceeded")
+
+// Limiter 本地限流器
+type Limiter interface {
+	// Allow reports whether a request is admitted now.
+	Allow() bool
+	// Wait blocks until a request is admitted or ctx is done,
+	// ErrLimitExceeded is returned if the request can't be admitted before ctx's deadline.
+	Wait(ctx context.Context) error
+	// Reserve reserves a request, the caller must wait for Delay before acting if OK,
+	// or call Cancel to give the reservation back.
+	Reserve() *Reservation
+}
+
+// Option 用于自定义 Limiter
+type Option func(opts *limiterOptions)
+
+type limiterOptions struct {
+	// 时钟，默认使用系统时间
+	clock timex.Clock
+	// Redis 不可用时使用的进程内限流器，只对 Redis 限流器有效
+	fallback Limiter
+}
+
+func newLimiterOptions(opts []Option) limiterOptions {
+	o := limiterOptions{
+		clock: timex.RealClock(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithClock customizes the clock of the Limiter, mostly used in tests.
+func WithClock(clock timex.Clock) Option {
+	return func(opts *limiterOptions) {
+		opts.clock = clock
+	}
+}
+
+// WithFallback customizes the in-process Limiter used when Redis is unreachable,
+// it only applies to the Redis limiters.
+func WithFallback(fallback Limiter) Option {
+	return func(opts *limiterOptions) {
+		opts.fallback = fallback
+	}
+}
+
+// A Reservation holds the result of Limiter.Reserve.
+type Reservation struct {
+	// 是否放行
+	ok bool
+	// 放行时为执行前需要等待的时间，不放行时为预计可以再次尝试的时间
+	delay time.Duration
+	// 归还预留的名额
+	cancel func()
+	once   sync.Once
+}
+
+// OK reports whether the request is admitted, possibly after Delay.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns how long the caller must wait before acting if OK,
+// otherwise the estimated duration before the limiter is likely to admit a request.
+func (r *Reservation) Delay() time.Duration {
+	return r.delay
+}
+
+// Cancel gives back the reservation if the request is not going to be made.
+// Cancel is a no-op if the reservation is not OK, already canceled,
+// or the limiter admits requests without delay, such as SlidingWindowLimiter.
+func (r *Reservation) Cancel() {
+	if !r.ok || r.cancel == nil {
+		return
+	}
+
+	r.once.Do(r.cancel)
+}
+
+// sleep 等待 d 或 ctx 结束
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
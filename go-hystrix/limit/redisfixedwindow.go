@@ -0,0 +1,144 @@
+package limit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+// KEYS[1]: 当前窗口的计数，第一个请求时设置过期时间为窗口长度
+// ARGV: quota, 窗口长度(ms)
+// 返回 {是否放行, 不放行时窗口剩余的时间(ms)}
+const fixedWindowScript = `local quota = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+local current = redis.call("INCR", KEYS[1])
+if current == 1 then
+    redis.call("PEXPIRE", KEYS[1], window)
+end
+if current <= quota then
+    return {1, 0}
+end
+
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+    redis.call("PEXPIRE", KEYS[1], window)
+    ttl = window
+end
+return {0, ttl}`
+
+// KEYS[1]: 当前窗口的计数
+// 窗口已经过期时不再归还，避免创建没有过期时间的计数
+const fixedWindowCancelScript = `if redis.call("EXISTS", KEYS[1]) == 1 then
+    return redis.call("DECR", KEYS[1])
+end
+return 0`
+
+var (
+	fixedWindow       = redis.NewScript(fixedWindowScript)
+	fixedWindowCancel = redis.NewScript(fixedWindowCancelScript)
+)
+
+// RedisFixedWindowLimiter 基于 Redis Lua 脚本的分布式固定窗口限流器，
+// 所有使用相同 key 的进程共享一个计数，窗口从第一个请求开始计时，每个窗口内最多放行 quota 个请求
+// Redis 不可用时降级为进程内限流器，并在 Redis 恢复后切换回来
+type RedisFixedWindowLimiter struct {
+	client   redis.UniversalClient
+	key      string
+	quota    int
+	window   time.Duration
+	fallback *redisFallback
+}
+
+// NewRedisFixedWindowLimiter returns a RedisFixedWindowLimiter on key, which allows quota requests
+// in each window across all the processes.
+// A SlidingWindowLimiter with the same quota and window is used when Redis is unreachable,
+// unless WithFallback is given.
+func NewRedisFixedWindowLimiter(client redis.UniversalClient, key string, quota int, window time.Duration,
+	opts ...Option) *RedisFixedWindowLimiter {
+	if quota < 1 {
+		panic("quota must be greater than 0")
+	}
+	if window < time.Millisecond {
+		panic("window must not be less than 1ms")
+	}
+
+	o := newLimiterOptions(opts)
+	local := o.fallback
+	if local == nil {
+		local = NewSlidingWindowLimiter(quota, window, fallbackBuckets(window), opts...)
+	}
+
+	return &RedisFixedWindowLimiter{
+		client:   client,
+		key:      key,
+		quota:    quota,
+		window:   window,
+		fallback: newRedisFallback(client, key, local),
+	}
+}
+
+// Allow reports whether a request is admitted now.
+func (l *RedisFixedWindowLimiter) Allow() bool {
+	r, _ := l.reserveCtx(context.Background(), false)
+	return r.ok
+}
+
+// Wait blocks until a request is admitted or ctx is done.
+// The limiter is checked again when the current window ends,
+// ErrLimitExceeded is returned if that's after ctx's deadline.
+func (l *RedisFixedWindowLimiter) Wait(ctx context.Context) error {
+	return waitReservation(ctx, func(ctx context.Context) (*Reservation, error) {
+		return l.reserveCtx(ctx, true)
+	})
+}
+
+// Reserve admits a request now with zero Delay if the quota of the current window is not used up,
+// otherwise it's not OK and Delay is the remaining duration of the current window.
+func (l *RedisFixedWindowLimiter) Reserve() *Reservation {
+	r, _ := l.reserveCtx(context.Background(), true)
+	return r
+}
+
+func (l *RedisFixedWindowLimiter) reserveCtx(ctx context.Context, wait bool) (*Reservation, error) {
+	return l.fallback.reserve(ctx, wait, l.reserve)
+}
+
+func (l *RedisFixedWindowLimiter) reserve(ctx context.Context) (*Reservation, error) {
+	result, err := fixedWindow.Run(ctx, l.client, []string{l.key},
+		strconv.Itoa(l.quota),
+		strconv.FormatInt(l.window.Milliseconds(), 10),
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := reservation(result)
+	if err != nil {
+		return nil, err
+	}
+	if r.ok {
+		r.cancel = l.cancel
+	}
+	return r, nil
+}
+
+func (l *RedisFixedWindowLimiter) cancel() {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	err := fixedWindowCancel.Run(ctx, l.client, []string{l.key}).Err()
+	if err != nil && err != redis.Nil {
+		l.fallback.fail(err)
+	}
+}
+
+// fallbackBuckets 进程内限流器的桶数，每个桶不少于 1ms
+func fallbackBuckets(window time.Duration) int {
+	const buckets = 10
+	if window < buckets*time.Millisecond {
+		return int(window / time.Millisecond)
+	}
+	return buckets
+}
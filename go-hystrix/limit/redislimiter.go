@@ -0,0 +1,157 @@
+package limit
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"github.com/tal-tech/go-zero/core/logx"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Redis 不可用时探测恢复的间隔
+	pingInterval = time.Millisecond * 100
+	// 单次 Redis 调用的超时时间，Redis 卡住时不阻塞调用方
+	redisTimeout = time.Millisecond * 100
+)
+
+// redisFallback Redis 不可用时切换到进程内限流器，并在后台探测 Redis 恢复
+type redisFallback struct {
+	client redis.UniversalClient
+	key    string
+	local  Limiter
+	// Redis 是否可用，不可用时请求由 local 限流
+	alive uint32
+	// 保证同一时间只有一个探测 goroutine
+	monitorLock    sync.Mutex
+	monitorStarted bool
+}
+
+func newRedisFallback(client redis.UniversalClient, key string, local Limiter) *redisFallback {
+	return &redisFallback{
+		client: client,
+		key:    key,
+		local:  local,
+		alive:  1,
+	}
+}
+
+// isAlive 返回 Redis 当前是否可用
+func (f *redisFallback) isAlive() bool {
+	return atomic.LoadUint32(&f.alive) == 1
+}
+
+// fail 记录 Redis 错误，切换到进程内限流器
+func (f *redisFallback) fail(err error) {
+	logx.Errorf("redis limiter %s falls back to in-process limiter: %s", f.key, err)
+	atomic.StoreUint32(&f.alive, 0)
+	f.startMonitor()
+}
+
+func (f *redisFallback) startMonitor() {
+	f.monitorLock.Lock()
+	defer f.monitorLock.Unlock()
+
+	if f.monitorStarted {
+		return
+	}
+
+	f.monitorStarted = true
+	go f.waitForRedis()
+}
+
+func (f *redisFallback) waitForRedis() {
+	ticker := time.NewTicker(pingInterval)
+	defer func() {
+		ticker.Stop()
+		f.monitorLock.Lock()
+		f.monitorStarted = false
+		f.monitorLock.Unlock()
+	}()
+
+	for range ticker.C {
+		if f.ping() {
+			atomic.StoreUint32(&f.alive, 1)
+			return
+		}
+	}
+}
+
+func (f *redisFallback) ping() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return f.client.Ping(ctx).Err() == nil
+}
+
+// reserve 在 Redis 可用时通过 reserveRedis 预留，Redis 出错时切换到进程内限流器
+// wait 为 false 时只判断是否放行，ctx 结束时返回 ctx.Err()，不视为 Redis 出错
+func (f *redisFallback) reserve(ctx context.Context, wait bool,
+	reserveRedis func(ctx context.Context) (*Reservation, error)) (*Reservation, error) {
+	if f.isAlive() {
+		redisCtx, cancel := context.WithTimeout(ctx, redisTimeout)
+		r, err := reserveRedis(redisCtx)
+		cancel()
+		if err == nil {
+			return r, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		f.fail(err)
+	}
+
+	if wait {
+		return f.local.Reserve(), nil
+	}
+	return &Reservation{
+		ok: f.local.Allow(),
+	}, nil
+}
+
+// reservation 将 Lua 脚本的返回值 {ok, delay(ms)} 转换为 Reservation
+func reservation(result interface{}) (*Reservation, error) {
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return nil, errUnexpectedResult
+	}
+
+	allowed, ok1 := values[0].(int64)
+	delay, ok2 := values[1].(int64)
+	if !ok1 || !ok2 {
+		return nil, errUnexpectedResult
+	}
+
+	return &Reservation{
+		ok:    allowed == 1,
+		delay: time.Duration(delay) * time.Millisecond,
+	}, nil
+}
+
+// waitReservation 实现 Wait：预留后等待 Delay，不放行时等待 Delay 后重试
+// 不能在 ctx 的截止时间前放行时返回 ErrLimitExceeded
+func waitReservation(ctx context.Context, reserve func(ctx context.Context) (*Reservation, error)) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r, err := reserve(ctx)
+		if err != nil {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < r.delay {
+			r.Cancel()
+			return ErrLimitExceeded
+		}
+		if err := sleep(ctx, r.delay); err != nil {
+			r.Cancel()
+			return err
+		}
+		if r.ok {
+			return nil
+		}
+	}
+}
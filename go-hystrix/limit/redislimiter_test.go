@@ -0,0 +1,234 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/timex"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingLimiter 记录被调用次数的降级限流器
+type countingLimiter struct {
+	Limiter
+	calls int32
+}
+
+func (l *countingLimiter) Allow() bool {
+	atomic.AddInt32(&l.calls, 1)
+	return l.Limiter.Allow()
+}
+
+func (l *countingLimiter) Reserve() *Reservation {
+	atomic.AddInt32(&l.calls, 1)
+	return l.Limiter.Reserve()
+}
+
+func (l *countingLimiter) count() int {
+	return int(atomic.LoadInt32(&l.calls))
+}
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	m, err := miniredis.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(m.Close)
+
+	client := redis.NewClient(&redis.Options{Addr: m.Addr()})
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return m, client
+}
+
+func TestRedisTokenBucketLimiter(t *testing.T) {
+	m, client := newTestRedis(t)
+	clock := timex.NewFakeClock(time.Now())
+	fallback := &countingLimiter{Limiter: NewTokenBucketLimiter(10, 2)}
+	l := NewRedisTokenBucketLimiter(client, "token", 10, 2, WithClock(clock), WithFallback(fallback))
+
+	tests := []struct {
+		advance time.Duration
+		allow   bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{50 * time.Millisecond, false},
+		{50 * time.Millisecond, true},
+		{0, false},
+		// 令牌数不超过 burst
+		{time.Second, true},
+		{0, true},
+		{0, false},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		if allow := l.Allow(); allow != test.allow {
+			t.Fatalf("#%d: Allow() = %v, want %v", i, allow, test.allow)
+		}
+	}
+
+	if !m.Exists("token") {
+		t.Fatal("token bucket is not stored in redis")
+	}
+	if n := fallback.count(); n != 0 {
+		t.Fatalf("fallback limiter called %d times, want 0", n)
+	}
+}
+
+func TestRedisTokenBucketLimiterReserveCancel(t *testing.T) {
+	_, client := newTestRedis(t)
+	clock := timex.NewFakeClock(time.Now())
+	l := NewRedisTokenBucketLimiter(client, "token", 10, 1, WithClock(clock))
+	if !l.Allow() {
+		t.Fatal("first request should be allowed")
+	}
+
+	r := l.Reserve()
+	if !r.OK() || r.Delay() != 100*time.Millisecond {
+		t.Fatalf("Reserve() = {%v, %v}, want {true, 100ms}", r.OK(), r.Delay())
+	}
+	r2 := l.Reserve()
+	if !r2.OK() || r2.Delay() != 200*time.Millisecond {
+		t.Fatalf("Reserve() = {%v, %v}, want {true, 200ms}", r2.OK(), r2.Delay())
+	}
+
+	// 归还两个预支的令牌后，100ms 后可以再放行一个请求
+	r2.Cancel()
+	r.Cancel()
+	clock.Advance(100 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("request should be allowed after the reservations are canceled")
+	}
+	if l.Allow() {
+		t.Fatal("request should be rejected once the token is used")
+	}
+}
+
+func TestRedisFixedWindowLimiter(t *testing.T) {
+	m, client := newTestRedis(t)
+	l := NewRedisFixedWindowLimiter(client, "window", 2, time.Second)
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("requests within quota should be allowed")
+	}
+
+	r := l.Reserve()
+	if r.OK() || r.Delay() <= 0 || r.Delay() > time.Second {
+		t.Fatalf("Reserve() = {%v, %v}, want not OK with the rest of the window", r.OK(), r.Delay())
+	}
+
+	m.FastForward(time.Second)
+	r = l.Reserve()
+	if !r.OK() || r.Delay() != 0 {
+		t.Fatalf("Reserve() = {%v, %v}, want {true, 0} in a new window", r.OK(), r.Delay())
+	}
+	r.Cancel()
+	if !l.Allow() || !l.Allow() {
+		t.Fatal("canceled reservation should give back its quota")
+	}
+	if l.Allow() {
+		t.Fatal("request over quota should be rejected")
+	}
+}
+
+func TestRedisFixedWindowLimiterWait(t *testing.T) {
+	_, client := newTestRedis(t)
+	l := NewRedisFixedWindowLimiter(client, "window", 1, time.Minute)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() = %v, want nil", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := l.Wait(ctx); err != ErrLimitExceeded {
+		t.Fatalf("Wait() = %v, want %v", err, ErrLimitExceeded)
+	}
+}
+
+func TestRedisLimiterFallback(t *testing.T) {
+	m, client := newTestRedis(t)
+	fallback := &countingLimiter{Limiter: NewTokenBucketLimiter(1000, 1000)}
+	l := NewRedisTokenBucketLimiter(client, "token", 1000, 1000, WithFallback(fallback))
+	if !l.Allow() || fallback.count() != 0 {
+		t.Fatal("request should be admitted by redis")
+	}
+
+	m.Close()
+	if !l.Allow() {
+		t.Fatal("request should be admitted by the fallback limiter when redis is down")
+	}
+	if !l.Allow() {
+		t.Fatal("request should be admitted by the fallback limiter when redis is down")
+	}
+	// 第一次请求失败后降级，之后不再访问 Redis
+	if n := fallback.count(); n != 2 {
+		t.Fatalf("fallback limiter called %d times, want 2", n)
+	}
+
+	if err := m.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	m.Del("token")
+	deadline := time.Now().Add(time.Second)
+	for !l.fallback.isAlive() {
+		if time.Now().After(deadline) {
+			t.Fatal("limiter doesn't switch back to redis after it recovers")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	n := fallback.count()
+	if !l.Allow() {
+		t.Fatal("request should be admitted by redis after it recovers")
+	}
+	if fallback.count() != n || !m.Exists("token") {
+		t.Fatal("request should go to redis after it recovers")
+	}
+}
+
+func TestRedisLimiterHungRedis(t *testing.T) {
+	// 接受连接但从不响应的 Redis
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String()})
+	defer client.Close()
+	l := NewRedisTokenBucketLimiter(client, "token", 1, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Wait() returned after %v, want it bounded by ctx", elapsed)
+	}
+
+	// 调用方没有截止时间时，超时后降级到进程内限流器
+	start = time.Now()
+	if !l.Allow() {
+		t.Fatal("request should be admitted by the fallback limiter when redis hangs")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Allow() returned after %v, want it bounded by redisTimeout", elapsed)
+	}
+}
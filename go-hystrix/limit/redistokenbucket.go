@@ -0,0 +1,185 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/timex"
+	"github.com/go-redis/redis/v8"
+	"strconv"
+	"time"
+)
+
+// KEYS[1]: 令牌桶的 hash，tokens 为当前令牌数，ts 为最后一次补充令牌的时间(ms)
+// ARGV: rate(个/秒), burst, now(ms), 令牌不足时是否预支, 过期时间(ms)
+// 返回 {是否放行, 需要等待的时间(ms)}
+const tokenBucketScript = `local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local wait = tonumber(ARGV[4])
+local ttl = tonumber(ARGV[5])
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+    tokens = burst
+    ts = now
+end
+if now > ts then
+    tokens = math.min(burst, tokens + (now - ts) * rate / 1000)
+    ts = now
+end
+
+if tokens < 1 and wait == 0 then
+    return {0, math.ceil((1 - tokens) * 1000 / rate)}
+end
+
+tokens = tokens - 1
+local delay = 0
+if tokens < 0 then
+    delay = math.ceil(-tokens * 1000 / rate)
+end
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", ts)
+redis.call("PEXPIRE", KEYS[1], ttl)
+return {1, delay}`
+
+// KEYS[1]: 令牌桶的 hash
+// ARGV: burst
+const tokenBucketCancelScript = `local tokens = tonumber(redis.call("HGET", KEYS[1], "tokens"))
+if tokens == nil then
+    return 0
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(math.min(tonumber(ARGV[1]), tokens + 1)))
+return 1`
+
+var (
+	errUnexpectedResult = errors.New("unexpected result of redis limiter script")
+
+	tokenBucket       = redis.NewScript(tokenBucketScript)
+	tokenBucketCancel = redis.NewScript(tokenBucketCancelScript)
+)
+
+// RedisTokenBucketLimiter 基于 Redis Lua 脚本的分布式令牌桶限流器，语义与 TokenBucketLimiter 相同，
+// 所有使用相同 key 的进程共享一个令牌桶
+// 补充令牌按调用方的时钟计算，各节点的时钟需要大致同步
+// Redis 不可用时降级为进程内限流器，并在 Redis 恢复后切换回来
+type RedisTokenBucketLimiter struct {
+	client redis.UniversalClient
+	key    string
+	rate   float64
+	burst  int
+	// 令牌桶的过期时间，取填满令牌桶所需时间的两倍
+	ttl      time.Duration
+	clock    timex.Clock
+	fallback *redisFallback
+}
+
+// NewRedisTokenBucketLimiter returns a RedisTokenBucketLimiter on key, which allows rate requests
+// per second with bursts of at most burst requests across all the processes.
+// A TokenBucketLimiter with the same rate and burst is used when Redis is unreachable,
+// unless WithFallback is given.
+func NewRedisTokenBucketLimiter(client redis.UniversalClient, key string, rate float64, burst int,
+	opts ...Option) *RedisTokenBucketLimiter {
+	if rate <= 0 {
+		panic("rate must be greater than 0")
+	}
+	if burst < 1 {
+		panic("burst must be greater than 0")
+	}
+
+	o := newLimiterOptions(opts)
+	local := o.fallback
+	if local == nil {
+		local = NewTokenBucketLimiter(rate, burst, opts...)
+	}
+	ttl := time.Duration(float64(burst) / rate * float64(time.Second) * 2)
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+
+	return &RedisTokenBucketLimiter{
+		client:   client,
+		key:      key,
+		rate:     rate,
+		burst:    burst,
+		ttl:      ttl,
+		clock:    o.clock,
+		fallback: newRedisFallback(client, key, local),
+	}
+}
+
+// Allow reports whether a request is admitted now.
+func (l *RedisTokenBucketLimiter) Allow() bool {
+	r, _ := l.reserveCtx(context.Background(), false)
+	return r.ok
+}
+
+// Wait blocks until a request is admitted or ctx is done.
+// ErrLimitExceeded is returned immediately if the request can't be admitted before ctx's deadline.
+func (l *RedisTokenBucketLimiter) Wait(ctx context.Context) error {
+	return waitReservation(ctx, func(ctx context.Context) (*Reservation, error) {
+		return l.reserveCtx(ctx, true)
+	})
+}
+
+// Reserve reserves a token, which is OK unless Redis fails and the fallback limiter rejects it,
+// the caller must wait for Delay before acting.
+func (l *RedisTokenBucketLimiter) Reserve() *Reservation {
+	r, _ := l.reserveCtx(context.Background(), true)
+	return r
+}
+
+func (l *RedisTokenBucketLimiter) reserveCtx(ctx context.Context, wait bool) (*Reservation, error) {
+	return l.fallback.reserve(ctx, wait, func(ctx context.Context) (*Reservation, error) {
+		return l.reserve(ctx, wait)
+	})
+}
+
+func (l *RedisTokenBucketLimiter) reserve(ctx context.Context, wait bool) (*Reservation, error) {
+	var waitArg string
+	if wait {
+		waitArg = "1"
+	} else {
+		waitArg = "0"
+	}
+
+	now := l.clock.Time()
+	result, err := tokenBucket.Run(ctx, l.client, []string{l.key},
+		strconv.FormatFloat(l.rate, 'f', -1, 64),
+		strconv.Itoa(l.burst),
+		strconv.FormatInt(now.UnixMilli(), 10),
+		waitArg,
+		strconv.FormatInt(l.ttl.Milliseconds(), 10),
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := reservation(result)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.ok {
+		at := now.Add(r.delay)
+		r.cancel = func() {
+			l.cancel(at)
+		}
+	}
+	return r, nil
+}
+
+// cancel 归还 at 时刻才能使用的令牌，已经到达执行时间的预留不再归还
+func (l *RedisTokenBucketLimiter) cancel(at time.Time) {
+	if !l.clock.Time().Before(at) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	err := tokenBucketCancel.Run(ctx, l.client, []string{l.key}, strconv.Itoa(l.burst)).Err()
+	if err != nil && err != redis.Nil {
+		l.fallback.fail(err)
+	}
+}
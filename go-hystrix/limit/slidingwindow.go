@@ -0,0 +1,111 @@
+package limit
+
+import (
+	"context"
+	"examples/go-hystrix/collection"
+	"sync"
+	"time"
+)
+
+// SlidingWindowLimiter 滑动窗口限流器，任意 window 时间内最多放行 quota 个请求
+// 请求按桶记录在 collection.RollingWindow 中，精度为一个桶的时间间隔
+type SlidingWindowLimiter struct {
+	quota    int64
+	interval time.Duration
+	// 放行记为 1，Sum 为窗口内放行的请求数
+	stat *collection.RollingWindow
+	// 保证统计和记录是原子的
+	lock sync.Mutex
+}
+
+// NewSlidingWindowLimiter returns a SlidingWindowLimiter that allows quota requests in any window,
+// the window is split into buckets buckets.
+func NewSlidingWindowLimiter(quota int, window time.Duration, buckets int,
+	opts ...Option) *SlidingWindowLimiter {
+	if quota < 1 {
+		panic("quota must be greater than 0")
+	}
+	if buckets < 1 || window < time.Duration(buckets) {
+		panic("buckets must be greater than 0 and not greater than window")
+	}
+
+	o := newLimiterOptions(opts)
+	interval := window / time.Duration(buckets)
+	return &SlidingWindowLimiter{
+		quota:    int64(quota),
+		interval: interval,
+		stat:     collection.NewRollingWindow(buckets, interval, collection.WithClock(o.clock)),
+	}
+}
+
+// Allow reports whether a request is admitted now.
+func (l *SlidingWindowLimiter) Allow() bool {
+	return l.Reserve().ok
+}
+
+// Wait blocks until a request is admitted or ctx is done.
+// The limiter is checked again when the estimated delay passes.
+// ErrLimitExceeded is returned if the request can't be admitted before ctx's deadline.
+func (l *SlidingWindowLimiter) Wait(ctx context.Context) error {
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		r := l.Reserve()
+		if r.ok {
+			return nil
+		}
+		// Delay 是上界，最少还需要等待 Delay - interval
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < r.delay-l.interval {
+			return ErrLimitExceeded
+		}
+		if err := sleep(ctx, r.delay); err != nil {
+			if err == context.DeadlineExceeded {
+				return ErrLimitExceeded
+			}
+			return err
+		}
+	}
+}
+
+// Reserve admits a request now with zero Delay if the quota is not used up,
+// otherwise it's not OK and Delay is the estimated duration before a request can be admitted.
+// The admitted request is counted immediately, so Cancel is a no-op.
+func (l *SlidingWindowLimiter) Reserve() *Reservation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// 由旧到新的各个桶的请求数
+	var counts []int64
+	var total int64
+	l.stat.Reduce(func(b *collection.Bucket) {
+		count := int64(b.Sum)
+		counts = append(counts, count)
+		total += count
+	})
+	if total < l.quota {
+		// 放行的请求无需等待，不支持取消：-1 可能写入与 +1 不同的桶，在 +1 过期后仍然留在窗口内
+		l.stat.Add(1)
+		return &Reservation{
+			ok: true,
+		}
+	}
+
+	return &Reservation{
+		delay: l.delay(counts, total),
+	}
+}
+
+// delay 估算窗口内的请求数降到 quota 以下需要的时间
+// 由旧到新第 i 个桶最多再经过 i+1 个时间间隔过期
+func (l *SlidingWindowLimiter) delay(counts []int64, total int64) time.Duration {
+	for i, count := range counts {
+		total -= count
+		if total < l.quota {
+			return time.Duration(i+1) * l.interval
+		}
+	}
+
+	return time.Duration(len(counts)) * l.interval
+}
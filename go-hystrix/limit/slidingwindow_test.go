@@ -0,0 +1,83 @@
+package limit
+
+import (
+	"context"
+	"examples/go-hystrix/timex"
+	"testing"
+	"time"
+)
+
+func TestSlidingWindowLimiterAllow(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	l := NewSlidingWindowLimiter(3, time.Second, 10, WithClock(clock))
+
+	tests := []struct {
+		advance time.Duration
+		allow   bool
+	}{
+		{0, true},
+		{300 * time.Millisecond, true},
+		{0, true},
+		{0, false},
+		// 第一个请求过期
+		{700 * time.Millisecond, true},
+		{0, false},
+		// 300ms 时的两个请求过期
+		{300 * time.Millisecond, true},
+		{0, true},
+		{0, false},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		if allow := l.Allow(); allow != test.allow {
+			t.Fatalf("#%d: Allow() = %v, want %v", i, allow, test.allow)
+		}
+	}
+}
+
+func TestSlidingWindowLimiterReserveDelay(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	l := NewSlidingWindowLimiter(2, time.Second, 10, WithClock(clock))
+	l.Allow()
+	clock.Advance(300 * time.Millisecond)
+	l.Allow()
+
+	r := l.Reserve()
+	if r.OK() || r.Delay() != 700*time.Millisecond {
+		t.Fatalf("Reserve() = %v/%v, want false/700ms", r.OK(), r.Delay())
+	}
+}
+
+func TestSlidingWindowLimiterCancelNoop(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	l := NewSlidingWindowLimiter(2, time.Second, 10, WithClock(clock))
+	l.Allow()
+	clock.Advance(900 * time.Millisecond)
+	l.Reserve().Cancel()
+
+	// 第一个请求过期后，窗口内仍有一个请求，取消不能让窗口多放行
+	clock.Advance(200 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("Allow() = false, want true")
+	}
+	if l.Allow() {
+		t.Fatal("Allow() = true, quota exceeded within one window")
+	}
+}
+
+func TestSlidingWindowLimiterWaitDeadline(t *testing.T) {
+	l := NewSlidingWindowLimiter(1, time.Second, 10)
+	l.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != ErrLimitExceeded {
+		t.Fatalf("Wait() = %v, want %v", err, ErrLimitExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); err != context.Canceled {
+		t.Fatalf("Wait() = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,131 @@
+package limit
+
+import (
+	"context"
+	"examples/go-hystrix/timex"
+	"math"
+	"sync"
+	"time"
+)
+
+// TokenBucketLimiter 令牌桶限流器
+// 令牌以 rate 个/秒的速度放入容量为 burst 的桶中，每个请求消耗一个令牌，
+// 令牌不足时 Reserve 和 Wait 预支令牌，按欠下的令牌数计算需要等待的时间
+type TokenBucketLimiter struct {
+	// 每秒放入的令牌数
+	rate float64
+	// 桶的容量
+	burst float64
+	clock timex.Clock
+
+	lock sync.Mutex
+	// 当前令牌数，预支后可能为负数
+	tokens float64
+	// 最后一次更新令牌数的时间
+	last time.Duration
+}
+
+// NewTokenBucketLimiter returns a TokenBucketLimiter that allows rate requests per second
+// with bursts of at most burst requests. The bucket is full initially.
+func NewTokenBucketLimiter(rate float64, burst int, opts ...Option) *TokenBucketLimiter {
+	if rate <= 0 {
+		panic("rate must be greater than 0")
+	}
+	if burst < 1 {
+		panic("burst must be greater than 0")
+	}
+
+	o := newLimiterOptions(opts)
+	return &TokenBucketLimiter{
+		rate:   rate,
+		burst:  float64(burst),
+		clock:  o.clock,
+		tokens: float64(burst),
+		last:   o.clock.Now(),
+	}
+}
+
+// Allow reports whether a request is admitted now.
+func (l *TokenBucketLimiter) Allow() bool {
+	return l.reserve(false).ok
+}
+
+// Wait blocks until a request is admitted or ctx is done.
+// ErrLimitExceeded is returned immediately if the request can't be admitted before ctx's deadline.
+func (l *TokenBucketLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	r := l.reserve(true)
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < r.delay {
+		r.Cancel()
+		return ErrLimitExceeded
+	}
+
+	if err := sleep(ctx, r.delay); err != nil {
+		r.Cancel()
+		return err
+	}
+
+	return nil
+}
+
+// Reserve reserves a token, which is always OK, the caller must wait for Delay before acting.
+func (l *TokenBucketLimiter) Reserve() *Reservation {
+	return l.reserve(true)
+}
+
+func (l *TokenBucketLimiter) reserve(wait bool) *Reservation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	l.advance(now)
+	if l.tokens < 1 && !wait {
+		return &Reservation{
+			delay: l.durationFor(1 - l.tokens),
+		}
+	}
+
+	l.tokens--
+	var delay time.Duration
+	if l.tokens < 0 {
+		delay = l.durationFor(-l.tokens)
+	}
+	at := now + delay
+	return &Reservation{
+		ok:    true,
+		delay: delay,
+		cancel: func() {
+			l.cancel(at)
+		},
+	}
+}
+
+// cancel 归还 at 时刻才能使用的令牌，已经到达执行时间的预留不再归还
+func (l *TokenBucketLimiter) cancel(at time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.clock.Now()
+	if now >= at {
+		return
+	}
+
+	l.advance(now)
+	l.tokens = math.Min(l.burst, l.tokens+1)
+}
+
+// advance 按经过的时间补充令牌
+func (l *TokenBucketLimiter) advance(now time.Duration) {
+	if elapsed := now - l.last; elapsed > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+elapsed.Seconds()*l.rate)
+	}
+	l.last = now
+}
+
+// durationFor 返回补充 tokens 个令牌需要的时间
+func (l *TokenBucketLimiter) durationFor(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate * float64(time.Second)))
+}
@@ -0,0 +1,70 @@
+package limit
+
+import (
+	"context"
+	"examples/go-hystrix/timex"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketLimiterAllow(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	l := NewTokenBucketLimiter(10, 2, WithClock(clock))
+
+	tests := []struct {
+		advance time.Duration
+		allow   bool
+	}{
+		{0, true},
+		{0, true},
+		{0, false},
+		{50 * time.Millisecond, false},
+		{50 * time.Millisecond, true},
+		{0, false},
+		// 令牌数不超过 burst
+		{time.Second, true},
+		{0, true},
+		{0, false},
+	}
+	for i, test := range tests {
+		clock.Advance(test.advance)
+		if allow := l.Allow(); allow != test.allow {
+			t.Fatalf("#%d: Allow() = %v, want %v", i, allow, test.allow)
+		}
+	}
+}
+
+func TestTokenBucketLimiterReserveCancel(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	l := NewTokenBucketLimiter(10, 1, WithClock(clock))
+	if !l.Allow() {
+		t.Fatal("Allow() = false on a full bucket")
+	}
+
+	r := l.Reserve()
+	if !r.OK() || r.Delay() != 100*time.Millisecond {
+		t.Fatalf("Reserve() = %v/%v, want true/100ms", r.OK(), r.Delay())
+	}
+	r.Cancel()
+	r.Cancel()
+
+	// 归还的令牌在 100ms 后可用，重复 Cancel 只归还一次
+	clock.Advance(100 * time.Millisecond)
+	if !l.Allow() {
+		t.Fatal("Allow() = false after Cancel")
+	}
+	if l.Allow() {
+		t.Fatal("Allow() = true, Cancel gave back more than one token")
+	}
+}
+
+func TestTokenBucketLimiterWaitDeadline(t *testing.T) {
+	l := NewTokenBucketLimiter(1, 1)
+	l.Allow()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx); err != ErrLimitExceeded {
+		t.Fatalf("Wait() = %v, want %v", err, ErrLimitExceeded)
+	}
+}
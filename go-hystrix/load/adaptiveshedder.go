@@ -0,0 +1,288 @@
+package load
+
+import (
+	"errors"
+	"examples/go-hystrix/collection"
+	"examples/go-hystrix/timex"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultBuckets = 50
+	defaultWindow  = time.Second * 5
+	// 千分制，900 表示 90%
+	defaultCpuThreshold = 900
+	defaultMinRt        = float64(time.Second / time.Millisecond)
+	// 计算 flying 请求数的滑动平均系数
+	flyingBeta = 0.9
+	// 丢弃请求后保持过热状态的时间，避免 cpu 刚降下来时流量再次打满
+	coolOffDuration = time.Second
+)
+
+// ErrServiceOverloaded is returned by Shedder.Allow when the service is overloaded.
+var ErrServiceOverloaded = errors.New("service overloaded")
+
+// Promise 请求放行后的回调，请求结束时必须调用 Pass 或 Fail
+type Promise interface {
+	// Pass lets the caller tell that the call is successful.
+	Pass()
+	// Fail lets the caller tell that the call is failed.
+	Fail()
+}
+
+// Shedder 服务端自适应降载器
+type Shedder interface {
+	// Allow returns the Promise if allowed, otherwise ErrServiceOverloaded.
+	Allow() (Promise, error)
+	// Stat returns the statistics of the Shedder since it's created.
+	Stat() ShedderStat
+}
+
+// ShedderStat 降载统计
+type ShedderStat struct {
+	// 总请求数
+	Total int64
+	// 放行的请求数
+	Pass int64
+	// 丢弃的请求数
+	Drop int64
+	// 当前正在处理的请求数
+	Flying int64
+	// 最近一次统计的 cpu 使用率，千分制
+	CpuUsage int64
+	// 窗口内最大通过数 × 最小响应时间估算出的最大并发数
+	MaxFlight int64
+}
+
+// ShedderOption 用于自定义 Shedder
+type ShedderOption func(opts *shedderOptions)
+
+type shedderOptions struct {
+	window       time.Duration
+	buckets      int
+	cpuThreshold int64
+	clock        timex.Clock
+}
+
+// WithBuckets customizes the number of buckets of the Shedder's rolling window.
+func WithBuckets(buckets int) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.buckets = buckets
+	}
+}
+
+// WithWindow customizes the duration of the Shedder's rolling window.
+func WithWindow(window time.Duration) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.window = window
+	}
+}
+
+// WithCpuThreshold customizes the cpu threshold in 1000m notation, 900 means 90%.
+func WithCpuThreshold(threshold int64) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.cpuThreshold = threshold
+	}
+}
+
+// WithClock customizes the clock of the Shedder, mostly used in tests.
+func WithClock(clock timex.Clock) ShedderOption {
+	return func(opts *shedderOptions) {
+		opts.clock = clock
+	}
+}
+
+// adaptiveShedder BBR 风格的自适应降载
+// cpu 超过阈值(或刚降载过)且正在处理的请求数超过 最大通过数 × 最小响应时间 时丢弃请求
+type adaptiveShedder struct {
+	cpuThreshold int64
+	// 每秒的桶数，用于把每个桶的通过数换算成每秒，桶长不能整除 1s 或超过 1s 时为小数
+	bucketsPerSecond float64
+	// 正在处理的请求数
+	flying int64
+	// flying 的滑动平均值
+	avgFlying float64
+	// 保护 avgFlying
+	avgFlyingLock sync.Mutex
+	// 最近一次丢弃请求的时间，0 表示没有丢弃过
+	dropTime        int64
+	droppedRecently int32
+	// 每个桶内通过的请求数
+	passCounter *collection.RollingWindow
+	// 每个桶内请求的响应时间(毫秒)
+	rtCounter *collection.RollingWindow
+	clock     timex.Clock
+	// 返回 cpu 使用率，默认为 CpuUsage
+	cpuUsage func() int64
+
+	total int64
+	pass  int64
+	drop  int64
+}
+
+// NewAdaptiveShedder returns an adaptive Shedder.
+// NewAdaptiveShedder panics if the given options are invalid.
+func NewAdaptiveShedder(opts ...ShedderOption) Shedder {
+	options := shedderOptions{
+		window:       defaultWindow,
+		buckets:      defaultBuckets,
+		cpuThreshold: defaultCpuThreshold,
+		clock:        timex.RealClock(),
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.buckets < 1 {
+		panic("buckets must be greater than 0")
+	}
+	bucketDuration := options.window / time.Duration(options.buckets)
+	if bucketDuration <= 0 {
+		panic("window must be longer than buckets nanoseconds")
+	}
+
+	return &adaptiveShedder{
+		cpuThreshold:     options.cpuThreshold,
+		bucketsPerSecond: float64(time.Second) / float64(bucketDuration),
+		passCounter: collection.NewRollingWindow(options.buckets, bucketDuration,
+			collection.IgnoreCurrentBucket(), collection.WithClock(options.clock)),
+		rtCounter: collection.NewRollingWindow(options.buckets, bucketDuration,
+			collection.IgnoreCurrentBucket(), collection.WithClock(options.clock)),
+		clock:    options.clock,
+		cpuUsage: CpuUsage,
+	}
+}
+
+func (as *adaptiveShedder) Allow() (Promise, error) {
+	atomic.AddInt64(&as.total, 1)
+	if as.shouldDrop() {
+		atomic.StoreInt64(&as.dropTime, int64(as.clock.Now()))
+		atomic.StoreInt32(&as.droppedRecently, 1)
+		atomic.AddInt64(&as.drop, 1)
+		return nil, ErrServiceOverloaded
+	}
+
+	as.addFlying(1)
+	atomic.AddInt64(&as.pass, 1)
+
+	return &promise{
+		start:   as.clock.Now(),
+		shedder: as,
+	}, nil
+}
+
+func (as *adaptiveShedder) Stat() ShedderStat {
+	return ShedderStat{
+		Total:     atomic.LoadInt64(&as.total),
+		Pass:      atomic.LoadInt64(&as.pass),
+		Drop:      atomic.LoadInt64(&as.drop),
+		Flying:    atomic.LoadInt64(&as.flying),
+		CpuUsage:  as.cpuUsage(),
+		MaxFlight: as.maxFlight(),
+	}
+}
+
+func (as *adaptiveShedder) addFlying(delta int64) {
+	flying := atomic.AddInt64(&as.flying, delta)
+	// 请求完成时更新 avgFlying，让 avgFlying 比 flying 更平滑
+	// 如果在请求进入时更新，请求数增长时 avgFlying 会偏大，导致降载不及时
+	if delta < 0 {
+		as.avgFlyingLock.Lock()
+		as.avgFlying = as.avgFlying*flyingBeta + float64(flying)*(1-flyingBeta)
+		as.avgFlyingLock.Unlock()
+	}
+}
+
+func (as *adaptiveShedder) highThru() bool {
+	as.avgFlyingLock.Lock()
+	avgFlying := as.avgFlying
+	as.avgFlyingLock.Unlock()
+	maxFlight := as.maxFlight()
+	return int64(avgFlying) > maxFlight && atomic.LoadInt64(&as.flying) > maxFlight
+}
+
+// maxFlight 估算系统能承受的最大并发数：每秒最大通过数 × 最小响应时间(秒)
+func (as *adaptiveShedder) maxFlight() int64 {
+	// maxQPS = maxPASS * bucketsPerSecond
+	// minRT = min average response time in milliseconds
+	// maxQPS * minRT / milliseconds_per_second
+	return int64(math.Max(1, float64(as.maxPass())*as.bucketsPerSecond*(as.minRt()/1e3)))
+}
+
+func (as *adaptiveShedder) maxPass() int64 {
+	var result float64 = 1
+
+	as.passCounter.Reduce(func(b *collection.Bucket) {
+		if b.Sum > result {
+			result = b.Sum
+		}
+	})
+
+	return int64(result)
+}
+
+func (as *adaptiveShedder) minRt() float64 {
+	result := defaultMinRt
+
+	as.rtCounter.Reduce(func(b *collection.Bucket) {
+		if b.Count <= 0 {
+			return
+		}
+
+		avg := math.Round(b.Sum / float64(b.Count))
+		if avg < result {
+			result = avg
+		}
+	})
+
+	return result
+}
+
+func (as *adaptiveShedder) shouldDrop() bool {
+	if as.systemOverloaded() || as.stillHot() {
+		return as.highThru()
+	}
+
+	return false
+}
+
+// stillHot 最近 coolOffDuration 内丢弃过请求
+func (as *adaptiveShedder) stillHot() bool {
+	if atomic.LoadInt32(&as.droppedRecently) == 0 {
+		return false
+	}
+
+	dropTime := time.Duration(atomic.LoadInt64(&as.dropTime))
+	if dropTime == 0 {
+		return false
+	}
+
+	hot := as.clock.Since(dropTime) < coolOffDuration
+	if !hot {
+		atomic.StoreInt32(&as.droppedRecently, 0)
+	}
+
+	return hot
+}
+
+func (as *adaptiveShedder) systemOverloaded() bool {
+	return as.cpuUsage() >= as.cpuThreshold
+}
+
+type promise struct {
+	start   time.Duration
+	shedder *adaptiveShedder
+}
+
+func (p *promise) Fail() {
+	p.shedder.addFlying(-1)
+}
+
+func (p *promise) Pass() {
+	rt := float64(p.shedder.clock.Since(p.start)) / float64(time.Millisecond)
+	p.shedder.addFlying(-1)
+	p.shedder.rtCounter.Add(math.Ceil(rt))
+	p.shedder.passCounter.Add(1)
+}
@@ -0,0 +1,117 @@
+package load
+
+import (
+	"examples/go-hystrix/timex"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestShedder(clock timex.Clock, cpu *int64, opts ...ShedderOption) *adaptiveShedder {
+	shedder := NewAdaptiveShedder(append([]ShedderOption{WithClock(clock)}, opts...)...).(*adaptiveShedder)
+	shedder.cpuUsage = func() int64 {
+		return atomic.LoadInt64(cpu)
+	}
+	return shedder
+}
+
+func TestAdaptiveShedderMaxFlight(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		// 每个桶的通过数
+		pass int
+		want int64
+	}{
+		{"100ms buckets", 5 * time.Second, 10, 10},
+		// 桶长不能整除 1s
+		{"300ms buckets", 15 * time.Second, 30, 10},
+		// 桶长超过 1s
+		{"2s buckets", 100 * time.Second, 200, 10},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := timex.NewFakeClock(time.Now())
+			var cpu int64
+			shedder := newTestShedder(clock, &cpu, WithWindow(test.window), WithBuckets(defaultBuckets))
+			// 每个请求耗时 100ms，最大 QPS 为 100
+			for i := 0; i < test.pass; i++ {
+				shedder.passCounter.Add(1)
+				shedder.rtCounter.Add(100)
+			}
+			clock.Advance(test.window / defaultBuckets)
+			if got := shedder.maxFlight(); got != test.want {
+				t.Fatalf("maxFlight() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAdaptiveShedderPromise(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	var cpu int64
+	shedder := newTestShedder(clock, &cpu)
+
+	pass, err := shedder.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail, err := shedder.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flying := shedder.Stat().Flying; flying != 2 {
+		t.Fatalf("Flying = %d, want 2", flying)
+	}
+
+	clock.Advance(20 * time.Millisecond)
+	pass.Pass()
+	fail.Fail()
+	stat := shedder.Stat()
+	if stat.Total != 2 || stat.Pass != 2 || stat.Drop != 0 || stat.Flying != 0 {
+		t.Fatalf("Stat() = %+v, want 2 total, 2 pass and nothing flying", stat)
+	}
+
+	// 只有 Pass 记录响应时间，忽略当前桶
+	clock.Advance(time.Second / 10)
+	if rt := shedder.minRt(); rt != 20 {
+		t.Fatalf("minRt() = %v, want 20", rt)
+	}
+	if maxPass := shedder.maxPass(); maxPass != 1 {
+		t.Fatalf("maxPass() = %d, want 1", maxPass)
+	}
+}
+
+func TestAdaptiveShedderDrop(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	cpu := int64(defaultCpuThreshold)
+	shedder := newTestShedder(clock, &cpu)
+
+	// 空窗口的最大并发数为 10
+	maxFlight := shedder.maxFlight()
+	for i := int64(0); i <= maxFlight; i++ {
+		if _, err := shedder.Allow(); err != nil {
+			t.Fatalf("Allow() = %v before reaching max flight", err)
+		}
+	}
+	shedder.avgFlying = float64(maxFlight + 1)
+
+	if _, err := shedder.Allow(); err != ErrServiceOverloaded {
+		t.Fatalf("Allow() = %v, want %v", err, ErrServiceOverloaded)
+	}
+	if drop := shedder.Stat().Drop; drop != 1 {
+		t.Fatalf("Drop = %d, want 1", drop)
+	}
+
+	// cpu 降下来后仍在冷却时间内，继续丢弃
+	atomic.StoreInt64(&cpu, 0)
+	clock.Advance(coolOffDuration / 2)
+	if _, err := shedder.Allow(); err != ErrServiceOverloaded {
+		t.Fatalf("Allow() = %v while still hot, want %v", err, ErrServiceOverloaded)
+	}
+
+	clock.Advance(coolOffDuration)
+	if _, err := shedder.Allow(); err != nil {
+		t.Fatalf("Allow() = %v after cooling off, want nil", err)
+	}
+}
@@ -0,0 +1,46 @@
+package load
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// cpu 采样间隔
+	cpuRefreshInterval = time.Millisecond * 250
+	// 滑动平均系数，越大越平滑
+	cpuBeta = 0.95
+)
+
+var (
+	// 最近一段时间的 cpu 使用率，千分制，900 表示 90%
+	cpuUsage  int64
+	cpuSample sync.Once
+)
+
+// CpuUsage returns the moving average cpu usage of the host in 1000m notation, 900 means 90%.
+// The sampler starts on the first call, 0 is returned on platforms without /proc.
+func CpuUsage() int64 {
+	cpuSample.Do(startCpuSampler)
+	return atomic.LoadInt64(&cpuUsage)
+}
+
+func startCpuSampler() {
+	if !cpuSupported() {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(cpuRefreshInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			curUsage := int64(readCpuUsage())
+			prevUsage := atomic.LoadInt64(&cpuUsage)
+			// cpu = cpuᵗ⁻¹ * beta + cpuᵗ * (1 - beta)
+			usage := int64(float64(prevUsage)*cpuBeta + float64(curUsage)*(1-cpuBeta))
+			atomic.StoreInt64(&cpuUsage, usage)
+		}
+	}()
+}
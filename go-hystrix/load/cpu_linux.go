@@ -0,0 +1,88 @@
+package load
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const procStat = "/proc/stat"
+
+var errBadProcStat = errors.New("bad format of /proc/stat")
+
+// 上一次采样时 cpu 的总时间及空闲时间
+var preTotal, preIdle uint64
+
+func cpuSupported() bool {
+	total, idle, err := readProcStat()
+	if err != nil {
+		return false
+	}
+
+	preTotal, preIdle = total, idle
+	return true
+}
+
+// readCpuUsage 返回两次采样之间的 cpu 使用率，千分制
+// 只在采样 goroutine 中调用
+func readCpuUsage() uint64 {
+	total, idle, err := readProcStat()
+	if err != nil {
+		return 0
+	}
+
+	totalDelta := total - preTotal
+	idleDelta := idle - preIdle
+	preTotal, preIdle = total, idle
+	if totalDelta == 0 || idleDelta > totalDelta {
+		return 0
+	}
+
+	return (totalDelta - idleDelta) * 1000 / totalDelta
+}
+
+// readProcStat 读取 /proc/stat 第一行，格式为
+// cpu  user nice system idle iowait irq softirq steal guest guest_nice
+func readProcStat() (total, idle uint64, err error) {
+	f, err := os.Open(procStat)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return parseProcStat(line)
+}
+
+// parseProcStat 解析 /proc/stat 的 cpu 汇总行，返回 cpu 的总时间及空闲时间
+func parseProcStat(line string) (total, idle uint64, err error) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, errBadProcStat
+	}
+
+	for i, field := range fields[1:] {
+		// guest 和 guest_nice 已经包含在 user 和 nice 中
+		if i >= 8 {
+			break
+		}
+		v, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+		total += v
+		// idle 和 iowait
+		if i == 3 || i == 4 {
+			idle += v
+		}
+	}
+
+	return total, idle, nil
+}
@@ -0,0 +1,29 @@
+package load
+
+import "testing"
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		line  string
+		total uint64
+		idle  uint64
+		err   bool
+	}{
+		{"cpu  10 20 30 40 50 60 70 80\n", 360, 90, false},
+		// guest 和 guest_nice 不计入总时间
+		{"cpu  10 20 30 40 50 60 70 80 90 100\n", 360, 90, false},
+		{"cpu  1 2 3 4\n", 10, 4, false},
+		{"cpu  1 2 3\n", 0, 0, true},
+		{"cpu0 1 2 3 4 5\n", 0, 0, true},
+		{"cpu  1 2 x 4 5\n", 0, 0, true},
+	}
+	for _, test := range tests {
+		total, idle, err := parseProcStat(test.line)
+		if (err != nil) != test.err {
+			t.Fatalf("parseProcStat(%q) error = %v, want error %v", test.line, err, test.err)
+		}
+		if total != test.total || idle != test.idle {
+			t.Fatalf("parseProcStat(%q) = (%d, %d), want (%d, %d)", test.line, total, idle, test.total, test.idle)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package load
+
+func cpuSupported() bool {
+	return false
+}
+
+func readCpuUsage() uint64 {
+	return 0
+}
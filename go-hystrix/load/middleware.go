@@ -0,0 +1,61 @@
+package load
+
+import "net/http"
+
+// Middleware returns an http middleware that consults shedder before handling the request.
+// Overloaded requests get 503 Service Unavailable, panics and 5xx responses are reported as failures.
+func Middleware(shedder Shedder) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			promise, err := shedder.Allow()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+
+			recorder := &statusRecorder{ResponseWriter: w}
+			// handler panic 时也要结束 promise，否则 flying 永远不会减少
+			defer func() {
+				if e := recover(); e != nil {
+					promise.Fail()
+					panic(e)
+				}
+
+				if recorder.status >= http.StatusInternalServerError {
+					promise.Fail()
+				} else {
+					promise.Pass()
+				}
+			}()
+
+			next.ServeHTTP(recorder, r)
+		})
+	}
+}
+
+// statusRecorder 记录响应状态码
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher if the underlying ResponseWriter supports it.
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,60 @@
+package load
+
+import (
+	"examples/go-hystrix/timex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestMiddleware(t *testing.T) {
+	clock := timex.NewFakeClock(time.Now())
+	var cpu int64
+	shedder := newTestShedder(clock, &cpu)
+	handler := Middleware(shedder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/panic":
+			panic("boom")
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	serve := func(path string) int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	if code := serve("/ok"); code != http.StatusOK {
+		t.Fatalf("GET /ok = %d, want %d", code, http.StatusOK)
+	}
+	if code := serve("/error"); code != http.StatusInternalServerError {
+		t.Fatalf("GET /error = %d, want %d", code, http.StatusInternalServerError)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic of the handler is swallowed")
+			}
+		}()
+		serve("/panic")
+	}()
+	if flying := shedder.Stat().Flying; flying != 0 {
+		t.Fatalf("Flying = %d, want 0 after the handlers return", flying)
+	}
+
+	// 过载时返回 503
+	shedder.cpuUsage = func() int64 {
+		return defaultCpuThreshold
+	}
+	maxFlight := shedder.maxFlight()
+	for i := int64(0); i <= maxFlight; i++ {
+		shedder.Allow()
+	}
+	shedder.avgFlying = float64(maxFlight + 1)
+	if code := serve("/ok"); code != http.StatusServiceUnavailable {
+		t.Fatalf("GET /ok = %d, want %d when overloaded", code, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Jitter 退避时间的随机策略
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+type Jitter int
+
+const (
+	// NoJitter 纯指数退避：min(cap, base * 2^attempt)
+	NoJitter Jitter = iota
+	// FullJitter 在 [0, min(cap, base * 2^attempt)) 之间随机
+	FullJitter
+	// DecorrelatedJitter 在 [base, prev * 3) 之间随机，不超过 cap
+	DecorrelatedJitter
+)
+
+// backoff 计算每次重试前的等待时间
+type backoff struct {
+	base   time.Duration
+	cap    time.Duration
+	jitter Jitter
+	// 上一次的等待时间，DecorrelatedJitter 使用
+	prev time.Duration
+}
+
+// next 返回第 attempt 次重试(从 0 开始)前的等待时间
+func (b *backoff) next(attempt int) time.Duration {
+	switch b.jitter {
+	case FullJitter:
+		return random(0, b.exponential(attempt))
+	case DecorrelatedJitter:
+		if b.prev < b.base {
+			b.prev = b.base
+		}
+		b.prev = minDuration(b.cap, random(b.base, b.prev*3))
+		return b.prev
+	default:
+		return b.exponential(attempt)
+	}
+}
+
+func (b *backoff) exponential(attempt int) time.Duration {
+	d := float64(b.base) * math.Pow(2, float64(attempt))
+	if d >= float64(b.cap) {
+		return b.cap
+	}
+	return time.Duration(d)
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+var (
+	// rand.New(...) returns a non thread safe object
+	r    = rand.New(rand.NewSource(time.Now().UnixNano()))
+	lock sync.Mutex
+)
+
+// random 返回 [min, max) 之间的随机时间
+func random(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+
+	lock.Lock()
+	d := min + time.Duration(r.Int63n(int64(max-min)))
+	lock.Unlock()
+	return d
+}
@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"examples/go-hystrix/collection"
+	"time"
+)
+
+const (
+	budgetWindow  = time.Second * 10
+	budgetBuckets = 40
+)
+
+// A Budget limits the ratio of retries to requests in a rolling window,
+// so that retries can't multiply the load on a struggling downstream.
+// A Budget can be shared by all the calls to the same downstream.
+type Budget struct {
+	// 允许的重试数与请求数的比例
+	ratio float64
+	// 窗口内总是允许的重试数，避免低流量时无法重试
+	minRetries int64
+	// 请求记为 0，重试记为 1，Sum 为重试数，Count - Sum 为请求数
+	stat *collection.RollingWindow
+}
+
+// NewBudget returns a Budget that allows retries up to ratio of the requests in the last 10 seconds,
+// plus minRetries retries anyway.
+func NewBudget(ratio float64, minRetries int64, opts ...collection.RollingWindowOption) *Budget {
+	if ratio < 0 {
+		panic("ratio must not be negative")
+	}
+	if minRetries < 0 {
+		panic("min retries must not be negative")
+	}
+
+	bucketDuration := budgetWindow / budgetBuckets
+	return &Budget{
+		ratio:      ratio,
+		minRetries: minRetries,
+		stat:       collection.NewRollingWindow(budgetBuckets, bucketDuration, opts...),
+	}
+}
+
+// request 记录一次首次请求
+func (b *Budget) request() {
+	b.stat.Add(0)
+}
+
+// tryRetry 预算足够时记录一次重试并返回 true
+func (b *Budget) tryRetry() bool {
+	var retries, total int64
+	b.stat.Reduce(func(bucket *collection.Bucket) {
+		retries += int64(bucket.Sum)
+		total += bucket.Count
+	})
+
+	requests := total - retries
+	if float64(retries+1) > b.ratio*float64(requests)+float64(b.minRetries) {
+		return false
+	}
+
+	b.stat.Add(1)
+	return true
+}
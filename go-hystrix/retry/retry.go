@@ -0,0 +1,181 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/breaker"
+	"time"
+)
+
+const (
+	defaultAttempts = 3
+	defaultBase     = time.Millisecond * 50
+	defaultCap      = time.Second * 2
+)
+
+// ErrBudgetExhausted is wrapped with the last error when the retry budget is exhausted.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Retryable is the func to check if the request should be retried on err.
+type Retryable func(err error) bool
+
+// Option defines the method to customize the retry policy.
+type Option func(opts *options)
+
+type options struct {
+	attempts  int
+	base      time.Duration
+	cap       time.Duration
+	jitter    Jitter
+	retryable Retryable
+	budget    *Budget
+}
+
+// WithAttempts customizes the max attempts including the first one.
+func WithAttempts(attempts int) Option {
+	return func(opts *options) {
+		opts.attempts = attempts
+	}
+}
+
+// WithBackoff customizes the base and the cap of the exponential backoff.
+func WithBackoff(base, cap time.Duration) Option {
+	return func(opts *options) {
+		opts.base = base
+		opts.cap = cap
+	}
+}
+
+// WithJitter customizes the jitter of the backoff, FullJitter by default.
+func WithJitter(jitter Jitter) Option {
+	return func(opts *options) {
+		opts.jitter = jitter
+	}
+}
+
+// WithRetryable customizes the classification of retryable errors.
+func WithRetryable(retryable Retryable) Option {
+	return func(opts *options) {
+		opts.retryable = retryable
+	}
+}
+
+// WithBudget limits the retries by the given budget, which is usually shared by a downstream.
+func WithBudget(budget *Budget) Option {
+	return func(opts *options) {
+		opts.budget = budget
+	}
+}
+
+// Do runs fn until it succeeds, the error is not retryable, the attempts are used up,
+// the budget is exhausted or ctx is done.
+// By default, errors are retryable except that ctx is done or a breaker rejects the request.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	o := options{
+		attempts:  defaultAttempts,
+		base:      defaultBase,
+		cap:       defaultCap,
+		jitter:    FullJitter,
+		retryable: defaultRetryable,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.attempts < 1 {
+		panic("attempts must be greater than 0")
+	}
+	if o.base <= 0 || o.cap < o.base {
+		panic("backoff base must be positive and not greater than cap")
+	}
+
+	bo := &backoff{
+		base:   o.base,
+		cap:    o.cap,
+		jitter: o.jitter,
+	}
+	if o.budget != nil {
+		o.budget.request()
+	}
+
+	var err error
+	for attempt := 0; attempt < o.attempts; attempt++ {
+		if attempt > 0 {
+			if o.budget != nil && !o.budget.tryRetry() {
+				return &budgetError{err: err}
+			}
+			if e := sleep(ctx, bo.next(attempt-1)); e != nil {
+				return e
+			}
+		}
+
+		if err = fn(ctx); err == nil || !o.retryable(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// DoWithBreaker runs fn through b with retries, acceptable is passed to b.DoCtxWithAcceptable.
+// Retries stop as soon as b rejects the request whatever WithRetryable says,
+// so that retries never hammer an open breaker.
+func DoWithBreaker(ctx context.Context, b breaker.Breaker, fn func(ctx context.Context) error,
+	acceptable breaker.Acceptable, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	retryable := defaultRetryable
+	if o.retryable != nil {
+		retryable = func(err error) bool {
+			return !isBreakerRejection(err) && o.retryable(err)
+		}
+	}
+
+	return Do(ctx, func(ctx context.Context) error {
+		return b.DoCtxWithAcceptable(ctx, fn, acceptable)
+	}, append(opts[:len(opts):len(opts)], WithRetryable(retryable))...)
+}
+
+// defaultRetryable ctx 结束或熔断器拒绝的请求不重试
+func defaultRetryable(err error) bool {
+	return !errors.Is(err, context.Canceled) &&
+		!errors.Is(err, context.DeadlineExceeded) &&
+		!isBreakerRejection(err)
+}
+
+// isBreakerRejection 是否是熔断器拒绝请求的错误
+func isBreakerRejection(err error) bool {
+	return errors.Is(err, breaker.ErrServiceUnavailable) || errors.Is(err, breaker.ErrMaxConcurrency)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// budgetError 预算耗尽时返回，保留最后一次请求的错误
+type budgetError struct {
+	err error
+}
+
+func (e *budgetError) Error() string {
+	return ErrBudgetExhausted.Error() + ": " + e.err.Error()
+}
+
+// Is makes errors.Is(err, ErrBudgetExhausted) true.
+func (e *budgetError) Is(target error) bool {
+	return target == ErrBudgetExhausted
+}
+
+// Unwrap returns the last error of the request.
+func (e *budgetError) Unwrap() error {
+	return e.err
+}
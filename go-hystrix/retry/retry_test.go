@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"examples/go-hystrix/breaker"
+	"testing"
+	"time"
+)
+
+var errTemporary = errors.New("temporary error")
+
+func TestDoRetriesUntilSuccess(t *testing.T) {
+	var calls int
+	err := Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls < 3 {
+			return errTemporary
+		}
+		return nil
+	}, WithBackoff(time.Millisecond, time.Millisecond))
+	if err != nil || calls != 3 {
+		t.Fatalf("Do() = %v after %d calls, want nil after 3 calls", err, calls)
+	}
+}
+
+func TestDoBudgetExhausted(t *testing.T) {
+	err := Do(context.Background(), func(context.Context) error {
+		return errTemporary
+	}, WithBudget(NewBudget(0, 0)))
+	if !errors.Is(err, ErrBudgetExhausted) || !errors.Is(err, errTemporary) {
+		t.Fatalf("Do() = %v, want ErrBudgetExhausted wrapping %v", err, errTemporary)
+	}
+}
+
+// countingBreaker 记录 DoCtxWithAcceptable 的调用次数
+type countingBreaker struct {
+	breaker.Breaker
+	calls int
+}
+
+func (b *countingBreaker) DoCtxWithAcceptable(ctx context.Context, req func(ctx context.Context) error,
+	acceptable breaker.Acceptable) error {
+	b.calls++
+	return b.Breaker.DoCtxWithAcceptable(ctx, req, acceptable)
+}
+
+func TestDoWithBreakerStopsOnRejection(t *testing.T) {
+	b := &countingBreaker{
+		Breaker: breaker.NewBreaker(breaker.WithHystrix(breaker.HystrixConfig{
+			ConsecutiveFailures: 1,
+			SleepWindow:         time.Minute,
+		})),
+	}
+	b.Do(func() error {
+		return errTemporary
+	})
+
+	// 即使自定义的分类认为所有错误都可以重试，熔断器拒绝后也不再重试
+	err := DoWithBreaker(context.Background(), b, func(context.Context) error {
+		return nil
+	}, func(err error) bool {
+		return err == nil
+	}, WithAttempts(5), WithBackoff(time.Millisecond, time.Millisecond), WithRetryable(func(error) bool {
+		return true
+	}))
+	if err != breaker.ErrServiceUnavailable || b.calls != 1 {
+		t.Fatalf("DoWithBreaker() = %v after %d attempts, want %v after 1 attempt",
+			err, b.calls, breaker.ErrServiceUnavailable)
+	}
+}
@@ -0,0 +1,88 @@
+package timex
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟接口，提供与 Now/Since/Time 相同语义的相对时间
+// 生产环境使用 RealClock，测试中可替换为 FakeClock 手动推进时间
+type Clock interface {
+	// Now returns a relative time duration since the clock's start time.
+	Now() time.Duration
+	// Since returns a diff since given d.
+	Since(d time.Duration) time.Duration
+	// Time returns current time of the clock.
+	Time() time.Time
+}
+
+// RealClock returns a Clock backed by the wall clock, the same as the package-level functions.
+func RealClock() Clock {
+	return realClock{}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Duration {
+	return Now()
+}
+
+func (realClock) Since(d time.Duration) time.Duration {
+	return Since(d)
+}
+
+func (realClock) Time() time.Time {
+	return Time()
+}
+
+// A FakeClock is a Clock that only moves when Advance or Set is called.
+type FakeClock struct {
+	// 起始时间，与 initTime 一样取足够早的时间，避免 Now() - lastTime 等于 0
+	start time.Time
+	// 相对 start 已经过去的时间
+	elapsed time.Duration
+	lock    sync.RWMutex
+}
+
+// NewFakeClock returns a FakeClock starting at given time.
+func NewFakeClock(start time.Time) *FakeClock {
+	// 与 RealClock 保持一致：Now() 始终是一个足够大的正数
+	base := start.AddDate(-1, -1, -1)
+	return &FakeClock{
+		start:   base,
+		elapsed: start.Sub(base),
+	}
+}
+
+// Now returns a relative time duration since the clock's start time.
+func (c *FakeClock) Now() time.Duration {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.elapsed
+}
+
+// Since returns a diff since given d.
+func (c *FakeClock) Since(d time.Duration) time.Duration {
+	return c.Now() - d
+}
+
+// Time returns current time of the clock.
+func (c *FakeClock) Time() time.Time {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.start.Add(c.elapsed)
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	c.elapsed += d
+	c.lock.Unlock()
+}
+
+// Set moves the clock to given time t, t must not be earlier than the clock's start time.
+func (c *FakeClock) Set(t time.Time) {
+	c.lock.Lock()
+	c.elapsed = t.Sub(c.start)
+	c.lock.Unlock()
+}